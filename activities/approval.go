@@ -1,8 +1,14 @@
 package activities
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
 	"time"
 
 	"go.temporal.io/sdk/activity"
@@ -10,61 +16,157 @@ import (
 	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
 )
 
-// ApprovalActivities provides human approval operations
+// defaultNotifierTimeout bounds a single backend's Send call when
+// ApprovalChannelConfig.Kinds fans a request out to several at once.
+const defaultNotifierTimeout = 30 * time.Second
+
+// ApprovalChannel sends a human-approval request through one concrete backend
+// (Slack, GitHub deployment approvals, email) and returns a correlation token
+// that cmd/approval-server verifies before signaling the workflow back.
+type ApprovalChannel interface {
+	Send(ctx context.Context, req shared.SendApprovalRequestRequest, correlationToken string) error
+}
+
+// ApprovalActivities provides human approval operations. The concrete
+// notification backend is selected per-request via shared.ApprovalChannelConfig.
 type ApprovalActivities struct{}
 
-// SendApprovalRequest sends a notification for approval
-func (a *ApprovalActivities) SendApprovalRequest(ctx context.Context, req shared.SendApprovalRequestRequest) (*shared.SendApprovalRequestResponse, error) {
+// SendApprovalRequest dispatches the approval request through the configured
+// channel and returns a correlation token that ties a callback (Slack button
+// click, GitHub review, reply email) back to this workflow run.
+func (a *ApprovalActivities) SendApprovalRequest(ctx context.Context, req shared.SendApprovalRequestRequest, config shared.ApprovalChannelConfig) (*shared.SendApprovalRequestResponse, error) {
 	logger := activity.GetLogger(ctx)
 	info := activity.GetInfo(ctx)
 
+	correlationToken := shared.EncodeCorrelationToken(info.WorkflowExecution.ID, info.WorkflowExecution.RunID)
+
+	kinds := config.Kinds
+	if len(kinds) == 0 {
+		kinds = []shared.ApprovalChannelKind{config.Kind}
+	}
+
+	timeout := config.NotifierTimeout
+	if timeout == 0 {
+		timeout = defaultNotifierTimeout
+	}
+
 	logger.Info("Sending approval request",
+		"channels", kinds,
 		"environment", req.Environment,
 		"imageTag", req.ImageTag,
-		"stagingURL", req.StagingURL,
-		"workflowID", info.WorkflowExecution.ID,
-		"runID", info.WorkflowExecution.RunID)
-
-	// In a real implementation, this would send notifications via Slack, email, etc.
-	// For the demo, we'll just log the approval request details
-
-	approvalMessage := fmt.Sprintf(`
-==================================================
-APPROVAL REQUIRED - Production Deployment
-==================================================
-Workflow ID: %s
-Image Tag: %s
-Environment: %s
-Staging URL: %s
-
-The application has been successfully deployed to staging.
-Please review the staging deployment and approve or reject
-the production deployment.
-
-To approve:
-  go run cmd/starter/main.go -action=approve -workflow=%s
-
-To reject:
-  go run cmd/starter/main.go -action=reject -workflow=%s
-
-To check status:
-  go run cmd/starter/main.go -action=status -workflow=%s
-==================================================
-`,
-		info.WorkflowExecution.ID,
-		req.ImageTag,
-		req.Environment,
-		req.StagingURL,
-		info.WorkflowExecution.ID,
-		info.WorkflowExecution.ID,
-		info.WorkflowExecution.ID)
-
-	logger.Info(approvalMessage)
+		"workflowID", info.WorkflowExecution.ID)
+
+	type result struct {
+		kind shared.ApprovalChannelKind
+		err  error
+	}
+	results := make([]result, len(kinds))
+	var wg sync.WaitGroup
+	for i, kind := range kinds {
+		wg.Add(1)
+		go func(i int, kind shared.ApprovalChannelKind) {
+			defer wg.Done()
+			defer func() {
+				// A panicking notifier (malformed response, unexpected nil, etc.)
+				// must not take down the worker process or the other channels'
+				// goroutines; record it as that channel's failure instead.
+				if p := recover(); p != nil {
+					results[i] = result{kind: kind, err: fmt.Errorf("panic: %v", p)}
+				}
+			}()
+
+			channel, err := newApprovalChannel(shared.ApprovalChannelConfig{ // copy with this goroutine's kind
+				Kind:                  kind,
+				SlackWebhookURL:       config.SlackWebhookURL,
+				SlackChannel:          config.SlackChannel,
+				GitHubOwner:           config.GitHubOwner,
+				GitHubRepo:            config.GitHubRepo,
+				GitHubEnvironment:     config.GitHubEnvironment,
+				GitHubToken:           config.GitHubToken,
+				EmailTo:               config.EmailTo,
+				EmailFrom:             config.EmailFrom,
+				SMTPServer:            config.SMTPServer,
+				PagerDutyRoutingKey:   config.PagerDutyRoutingKey,
+				WebhookURL:            config.WebhookURL,
+				WebhookSecret:         config.WebhookSecret,
+				CallbackBaseURL:       config.CallbackBaseURL,
+				CallbackSigningSecret: config.CallbackSigningSecret,
+			})
+			if err != nil {
+				results[i] = result{kind: kind, err: fmt.Errorf("failed to configure approval channel: %w", err)}
+				return
+			}
+
+			sendCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results[i] = result{kind: kind, err: channel.Send(sendCtx, req, correlationToken)}
+		}(i, kind)
+	}
+	wg.Wait()
+
+	var notificationIDs []string
+	var failures []string
+	for _, r := range results {
+		if r.err != nil {
+			logger.Error("Failed to send approval request", "channel", r.kind, "error", r.err)
+			failures = append(failures, fmt.Sprintf("%s: %v", r.kind, r.err))
+			continue
+		}
+		notificationIDs = append(notificationIDs, fmt.Sprintf("approval-%s-%s", r.kind, correlationToken))
+	}
+
+	if len(notificationIDs) == 0 {
+		return nil, fmt.Errorf("approval request failed on every configured channel: %s", strings.Join(failures, "; "))
+	}
+
+	message := "Approval request sent successfully"
+	if len(failures) > 0 {
+		message = fmt.Sprintf("Approval request sent to %d/%d channels; failures: %s", len(notificationIDs), len(kinds), strings.Join(failures, "; "))
+	}
 
 	return &shared.SendApprovalRequestResponse{
-		Success:        true,
-		NotificationID: fmt.Sprintf("approval-%s-%d", info.WorkflowExecution.ID, time.Now().Unix()),
-		Message:        "Approval request sent successfully",
+		Success:          true,
+		NotificationID:   notificationIDs[0],
+		NotificationIDs:  notificationIDs,
+		PartialFailure:   len(failures) > 0,
+		CorrelationToken: correlationToken,
+		Message:          message,
+	}, nil
+}
+
+// callbackURLs builds the Approve/Reject links embedded in every outbound
+// notification, appending an HMAC signature when config.CallbackSigningSecret
+// is set.
+func callbackURLs(config shared.ApprovalChannelConfig, correlationToken string) (approveURL, rejectURL string) {
+	sig := shared.SignCallbackToken(config.CallbackSigningSecret, correlationToken)
+	sigParam := ""
+	if sig != "" {
+		sigParam = "&sig=" + sig
+	}
+	approveURL = fmt.Sprintf("%s/approve?token=%s%s", config.CallbackBaseURL, correlationToken, sigParam)
+	rejectURL = fmt.Sprintf("%s/reject?token=%s%s", config.CallbackBaseURL, correlationToken, sigParam)
+	return approveURL, rejectURL
+}
+
+// EscalateApproval pages a secondary approver when the primary channel hasn't
+// produced a decision within the configured escalation timeout.
+func (a *ApprovalActivities) EscalateApproval(ctx context.Context, req shared.EscalateApprovalRequest) (*shared.EscalateApprovalResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Warn("Escalating approval request",
+		"environment", req.Environment,
+		"imageTag", req.ImageTag,
+		"secondaryApprover", req.SecondaryApprover,
+		"workflowID", req.WorkflowID)
+
+	// DEMO HELPER: a real implementation would page req.SecondaryApprover through
+	// PagerDuty/Opsgenie/a second Slack DM. We log loudly enough that it's obvious
+	// in the worker output during the workshop.
+	logger.Warn(fmt.Sprintf("ESCALATION: %s has not responded, paging %s for workflow %s",
+		req.Environment, req.SecondaryApprover, req.WorkflowID))
+
+	return &shared.EscalateApprovalResponse{
+		Success: true,
+		Message: fmt.Sprintf("escalated to %s", req.SecondaryApprover),
 	}, nil
 }
 
@@ -78,7 +180,6 @@ func (a *ApprovalActivities) LogApprovalDecision(ctx context.Context, req shared
 		"reason", req.Reason,
 		"timestamp", req.Timestamp)
 
-	// In a real implementation, this might update a database, send notifications, etc.
 	var message string
 	if req.Approved {
 		message = fmt.Sprintf("Deployment APPROVED by %s at %s", req.Approver, req.Timestamp.Format(time.RFC3339))
@@ -108,47 +209,11 @@ func (a *ApprovalActivities) SendApprovalNotification(ctx context.Context, req s
 		"approved", req.Approved,
 		"environment", req.Environment)
 
-	// Build notification message
 	var notificationMessage string
 	if req.Approved {
-		notificationMessage = fmt.Sprintf(`
-==================================================
-DEPLOYMENT APPROVED - Proceeding to Production
-==================================================
-Environment: %s
-Image Tag: %s
-Approved by: %s
-Time: %s
-
-The deployment has been approved and will now
-proceed to the production environment.
-==================================================
-`,
-			req.Environment,
-			req.ImageTag,
-			req.Approver,
-			time.Now().Format(time.RFC3339))
+		notificationMessage = fmt.Sprintf("Deployment APPROVED by %s for %s (%s)", req.Approver, req.Environment, req.ImageTag)
 	} else {
-		notificationMessage = fmt.Sprintf(`
-==================================================
-DEPLOYMENT REJECTED - Workflow Cancelled
-==================================================
-Environment: %s
-Image Tag: %s
-Rejected by: %s
-Reason: %s
-Time: %s
-
-The deployment has been rejected. The workflow
-has been cancelled and no changes will be made
-to the production environment.
-==================================================
-`,
-			req.Environment,
-			req.ImageTag,
-			req.Approver,
-			req.Reason,
-			time.Now().Format(time.RFC3339))
+		notificationMessage = fmt.Sprintf("Deployment REJECTED by %s for %s (%s): %s", req.Approver, req.Environment, req.ImageTag, req.Reason)
 	}
 
 	logger.Info(notificationMessage)
@@ -158,3 +223,259 @@ to the production environment.
 		Message: "Notification sent successfully",
 	}, nil
 }
+
+// newApprovalChannel selects the concrete ApprovalChannel implementation for config.Kind.
+func newApprovalChannel(config shared.ApprovalChannelConfig) (ApprovalChannel, error) {
+	switch config.Kind {
+	case shared.ApprovalChannelSlack, "":
+		return &SlackApprovalChannel{config: config}, nil
+	case shared.ApprovalChannelGitHub:
+		return &GitHubApprovalChannel{config: config}, nil
+	case shared.ApprovalChannelEmail:
+		return &EmailApprovalChannel{config: config}, nil
+	case shared.ApprovalChannelPagerDuty:
+		return &PagerDutyApprovalChannel{config: config}, nil
+	case shared.ApprovalChannelWebhook:
+		return &GenericWebhookApprovalChannel{config: config}, nil
+	default:
+		return nil, fmt.Errorf("unknown approval channel kind: %s", config.Kind)
+	}
+}
+
+// SlackApprovalChannel posts an interactive block-kit message with Approve/Reject
+// buttons. The buttons link to cmd/approval-server, which verifies the click and
+// signals the workflow.
+type SlackApprovalChannel struct {
+	config shared.ApprovalChannelConfig
+}
+
+func (s *SlackApprovalChannel) Send(ctx context.Context, req shared.SendApprovalRequestRequest, correlationToken string) error {
+	if s.config.SlackWebhookURL == "" {
+		return fmt.Errorf("slack approval channel requires SlackWebhookURL")
+	}
+
+	approveURL, rejectURL := callbackURLs(s.config, correlationToken)
+
+	payload := map[string]interface{}{
+		"channel": s.config.SlackChannel,
+		"blocks": []map[string]interface{}{
+			{
+				"type": "section",
+				"text": map[string]string{
+					"type": "mrkdwn",
+					"text": fmt.Sprintf("*Production deployment awaiting approval*\n*Image:* %s\n*Staging:* %s", req.ImageTag, req.StagingURL),
+				},
+			},
+			{
+				"type": "actions",
+				"elements": []map[string]interface{}{
+					{"type": "button", "text": map[string]string{"type": "plain_text", "text": "Approve"}, "style": "primary", "url": approveURL},
+					{"type": "button", "text": map[string]string{"type": "plain_text", "text": "Reject"}, "style": "danger", "url": rejectURL},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.SlackWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GitHubApprovalChannel requests a deployment review via the GitHub Deployments
+// API, gated by environment protection rules on config.GitHubEnvironment.
+type GitHubApprovalChannel struct {
+	config shared.ApprovalChannelConfig
+}
+
+func (g *GitHubApprovalChannel) Send(ctx context.Context, req shared.SendApprovalRequestRequest, correlationToken string) error {
+	if g.config.GitHubOwner == "" || g.config.GitHubRepo == "" || g.config.GitHubEnvironment == "" {
+		return fmt.Errorf("github approval channel requires GitHubOwner, GitHubRepo, and GitHubEnvironment")
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/deployments", g.config.GitHubOwner, g.config.GitHubRepo)
+	payload := map[string]interface{}{
+		"ref":               req.ImageTag,
+		"environment":       g.config.GitHubEnvironment,
+		"description":       fmt.Sprintf("Approve production deploy of %s (correlation token: %s)", req.ImageTag, correlationToken),
+		"auto_merge":        false,
+		"required_contexts": []string{},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal github deployment payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+g.config.GitHubToken)
+	httpReq.Header.Set("Accept", "application/vnd.github+json")
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to create github deployment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("github deployments API returned status %d", resp.StatusCode)
+	}
+	// The actual approve/reject happens via the environment's required reviewers
+	// on the GitHub side; cmd/approval-server polls the deployment status API and
+	// translates the review decision into a signal.
+	return nil
+}
+
+// EmailApprovalChannel sends an approval request with approve/reject links over
+// SMTP, for teams without Slack/GitHub access.
+type EmailApprovalChannel struct {
+	config shared.ApprovalChannelConfig
+}
+
+func (e *EmailApprovalChannel) Send(ctx context.Context, req shared.SendApprovalRequestRequest, correlationToken string) error {
+	if len(e.config.EmailTo) == 0 || e.config.SMTPServer == "" {
+		return fmt.Errorf("email approval channel requires EmailTo and SMTPServer")
+	}
+
+	approveURL, rejectURL := callbackURLs(e.config, correlationToken)
+
+	subject := fmt.Sprintf("Subject: Approve production deploy of %s\r\n", req.ImageTag)
+	body := fmt.Sprintf("Image: %s\r\nStaging: %s\r\n\r\nApprove: %s\r\nReject: %s\r\n", req.ImageTag, req.StagingURL, approveURL, rejectURL)
+	message := []byte(subject + "\r\n" + body)
+
+	// net/smtp.SendMail blocks on network I/O, which is exactly what activities
+	// (unlike workflow code) are allowed to do.
+	err := smtp.SendMail(e.config.SMTPServer, nil, e.config.EmailFrom, e.config.EmailTo, message)
+	if err != nil {
+		return fmt.Errorf("failed to send approval email: %w", err)
+	}
+	return nil
+}
+
+// PagerDutyApprovalChannel pages an on-call approver through PagerDuty's
+// Events API v2. PagerDuty incidents don't support interactive approve/reject
+// actions the way Slack/email links do, so the approve/reject URLs are
+// embedded in the incident's custom details for the on-call engineer to open.
+type PagerDutyApprovalChannel struct {
+	config shared.ApprovalChannelConfig
+}
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+func (p *PagerDutyApprovalChannel) Send(ctx context.Context, req shared.SendApprovalRequestRequest, correlationToken string) error {
+	if p.config.PagerDutyRoutingKey == "" {
+		return fmt.Errorf("pagerduty approval channel requires PagerDutyRoutingKey")
+	}
+
+	approveURL, rejectURL := callbackURLs(p.config, correlationToken)
+
+	payload := map[string]interface{}{
+		"routing_key":  p.config.PagerDutyRoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    correlationToken,
+		"payload": map[string]interface{}{
+			"summary":  fmt.Sprintf("Production deployment awaiting approval: %s", req.ImageTag),
+			"source":   "workshop-cicd-k8s-deployment",
+			"severity": "info",
+			"custom_details": map[string]string{
+				"imageTag":   req.ImageTag,
+				"stagingURL": req.StagingURL,
+				"approveURL": approveURL,
+				"rejectURL":  rejectURL,
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pagerduty event payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, pagerDutyEventsURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue pagerduty event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pagerduty events API returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// GenericWebhookApprovalChannel posts a JSON payload to an arbitrary endpoint,
+// for approval backends that aren't worth a dedicated implementation (internal
+// chat tools, ticketing systems, custom bots). The payload is HMAC-SHA256-signed
+// with config.WebhookSecret (when set) in an X-Signature header so the receiver
+// can verify it came from this pipeline.
+type GenericWebhookApprovalChannel struct {
+	config shared.ApprovalChannelConfig
+}
+
+func (g *GenericWebhookApprovalChannel) Send(ctx context.Context, req shared.SendApprovalRequestRequest, correlationToken string) error {
+	if g.config.WebhookURL == "" {
+		return fmt.Errorf("webhook approval channel requires WebhookURL")
+	}
+
+	approveURL, rejectURL := callbackURLs(g.config, correlationToken)
+
+	payload := map[string]interface{}{
+		"correlationToken": correlationToken,
+		"environment":      req.Environment,
+		"imageTag":         req.ImageTag,
+		"stagingURL":       req.StagingURL,
+		"approveURL":       approveURL,
+		"rejectURL":        rejectURL,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if sig := shared.SignCallbackToken(g.config.WebhookSecret, string(body)); sig != "" {
+		httpReq.Header.Set("X-Signature", sig)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to post approval webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("approval webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
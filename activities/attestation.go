@@ -0,0 +1,432 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+)
+
+// Scanner produces an SBOM for a built image and reports vulnerability
+// findings against it. This indirection lets AttestationActivities run
+// against syft/grype or a fake scanner in tests without changing
+// AttestationActivities itself.
+type Scanner interface {
+	GenerateSBOM(ctx context.Context, imageRef string) (shared.SBOM, error)
+	ScanVulnerabilities(ctx context.Context, imageRef string) ([]shared.VulnerabilityFinding, error)
+}
+
+// Signer signs images and attaches in-toto attestations (SBOM, provenance) to
+// them in the registry, and verifies both later in the pipeline.
+type Signer interface {
+	Sign(ctx context.Context, imageRef string) error
+	Attest(ctx context.Context, imageRef, predicateType, predicateJSON string) error
+	Verify(ctx context.Context, imageRef string) (bool, error)
+}
+
+// AttestationActivities provides the supply-chain activities that run after a
+// successful push: SBOM generation, SLSA provenance, cosign signing, and the
+// signature/SBOM policy check that gates promotion to production.
+type AttestationActivities struct {
+	Scanner Scanner
+	Signer  Signer
+}
+
+// NewAttestationActivities wires the scanner and signer backends used for
+// supply-chain attestation.
+func NewAttestationActivities(scanner Scanner, signer Signer) *AttestationActivities {
+	return &AttestationActivities{Scanner: scanner, Signer: signer}
+}
+
+// GenerateSBOM scans the built image and returns its software bill of
+// materials.
+func (a *AttestationActivities) GenerateSBOM(ctx context.Context, req shared.GenerateSBOMRequest) (*shared.GenerateSBOMResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Generating SBOM", "image", req.ImageRef, "format", req.Format)
+
+	if a.Scanner == nil {
+		return nil, fmt.Errorf("no scanner configured for SBOM generation")
+	}
+
+	sbom, err := a.Scanner.GenerateSBOM(ctx, req.ImageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate SBOM for %s: %w", req.ImageRef, err)
+	}
+
+	activity.RecordHeartbeat(ctx, "sbom generated")
+	return &shared.GenerateSBOMResponse{SBOM: sbom}, nil
+}
+
+// GenerateProvenance builds an in-toto SLSA v1.0 provenance statement for the
+// build that produced imageRef: the workflow/run that built it, the git
+// commit, the builder identity, and its materials (source inputs). It does no
+// I/O itself; SignAndAttach signs the statement and attaches it to the image.
+func (a *AttestationActivities) GenerateProvenance(ctx context.Context, req shared.GenerateProvenanceRequest) (*shared.GenerateProvenanceResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Generating SLSA provenance", "image", req.ImageRef, "workflowID", req.WorkflowID)
+
+	name, digest, ok := strings.Cut(req.ImageRef, "@")
+	if !ok {
+		return nil, fmt.Errorf("image ref %q is not digest-qualified (expected name@sha256:...)", req.ImageRef)
+	}
+
+	statement := shared.ProvenanceStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		PredicateType: "https://slsa.dev/provenance/v1",
+		Subject:       shared.ProvenanceSubject{Name: name, Digest: digest},
+		BuilderID:     req.BuilderID,
+		WorkflowID:    req.WorkflowID,
+		RunID:         req.RunID,
+		GitCommit:     req.GitCommit,
+		Materials:     req.Materials,
+		BuiltAt:       time.Now().UTC(),
+	}
+
+	return &shared.GenerateProvenanceResponse{Statement: statement}, nil
+}
+
+// SignAndAttach signs the image and attaches the SBOM and provenance
+// statement to it in the registry as cosign attestations, so
+// VerifyImagePolicy (and any external consumer) can later fetch and verify
+// them without access to the build pipeline.
+func (a *AttestationActivities) SignAndAttach(ctx context.Context, req shared.SignAndAttachRequest) (*shared.SignAndAttachResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Signing image and attaching attestations", "image", req.ImageRef)
+
+	if a.Signer == nil {
+		return nil, fmt.Errorf("no signer configured for image attestation")
+	}
+
+	if err := a.Signer.Sign(ctx, req.ImageRef); err != nil {
+		return nil, fmt.Errorf("failed to sign %s: %w", req.ImageRef, err)
+	}
+	activity.RecordHeartbeat(ctx, "image signed")
+
+	if err := a.Signer.Attest(ctx, req.ImageRef, "cyclonedx", req.SBOM.RawJSON); err != nil {
+		return nil, fmt.Errorf("failed to attach SBOM attestation to %s: %w", req.ImageRef, err)
+	}
+
+	provenanceJSON, err := json.Marshal(req.Provenance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal provenance statement: %w", err)
+	}
+	if err := a.Signer.Attest(ctx, req.ImageRef, "slsaprovenance1", string(provenanceJSON)); err != nil {
+		return nil, fmt.Errorf("failed to attach provenance attestation to %s: %w", req.ImageRef, err)
+	}
+
+	logger.Info("Image signed and attestations attached", "image", req.ImageRef)
+	return &shared.SignAndAttachResponse{Signature: req.ImageRef}, nil
+}
+
+// VerifyImagePolicy verifies the image's cosign signature and evaluates its
+// SBOM against policy (maximum vulnerability severity, license allowlist)
+// before CICDPipelineWorkflow promotes it to production. Policy failures are
+// reported in the response rather than as an error so the workflow can
+// surface a structured shared.PolicyViolation instead of a generic activity
+// failure.
+func (a *AttestationActivities) VerifyImagePolicy(ctx context.Context, req shared.VerifyImagePolicyRequest) (*shared.VerifyImagePolicyResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Verifying image signature and SBOM policy", "image", req.ImageRef)
+
+	if a.Signer == nil {
+		return nil, fmt.Errorf("no signer configured for signature verification")
+	}
+
+	var violations []string
+
+	verified, err := a.Signer.Verify(ctx, req.ImageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify signature for %s: %w", req.ImageRef, err)
+	}
+	if !verified {
+		violations = append(violations, "image signature could not be verified")
+	}
+
+	if a.Scanner != nil {
+		findings, err := a.Scanner.ScanVulnerabilities(ctx, req.ImageRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s for vulnerabilities: %w", req.ImageRef, err)
+		}
+		for _, f := range findings {
+			if severityExceeds(f.Severity, req.Policy.MaxSeverity) {
+				violations = append(violations, fmt.Sprintf("%s in %s has severity %s (max allowed: %s)", f.ID, f.Package, f.Severity, req.Policy.MaxSeverity))
+			}
+		}
+
+		if len(req.Policy.AllowedLicenses) > 0 {
+			sbom, err := a.Scanner.GenerateSBOM(ctx, req.ImageRef)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load SBOM for %s: %w", req.ImageRef, err)
+			}
+			for _, pkg := range sbom.Packages {
+				if pkg.License != "" && !licenseAllowed(pkg.License, req.Policy.AllowedLicenses) {
+					violations = append(violations, fmt.Sprintf("package %s@%s uses disallowed license %s", pkg.Name, pkg.Version, pkg.License))
+				}
+			}
+		}
+	}
+
+	activity.RecordHeartbeat(ctx, "policy evaluated")
+
+	resp := &shared.VerifyImagePolicyResponse{
+		SignatureVerified: verified,
+		Passed:            len(violations) == 0,
+		Violations:        violations,
+	}
+	logger.Info("Policy verification result", "passed", resp.Passed, "violations", len(violations))
+	return resp, nil
+}
+
+// ScanImage scans the built image for known vulnerabilities and returns a
+// severity-bucketed report. Unlike VerifyImagePolicy, which scans the
+// pushed-and-digest-addressed image to gate production promotion, ScanImage
+// runs against the local, pre-push image so CICDPipelineWorkflow can refuse
+// to publish a vulnerable image at all.
+func (a *AttestationActivities) ScanImage(ctx context.Context, req shared.ScanImageRequest) (*shared.ScanImageResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Scanning image for vulnerabilities", "image", req.ImageRef)
+
+	if a.Scanner == nil {
+		return nil, fmt.Errorf("no scanner configured for vulnerability scanning")
+	}
+
+	findings, err := a.Scanner.ScanVulnerabilities(ctx, req.ImageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s for vulnerabilities: %w", req.ImageRef, err)
+	}
+	activity.RecordHeartbeat(ctx, "scan complete")
+
+	var counts shared.VulnerabilitySeverityCounts
+	passed := true
+	for _, f := range findings {
+		switch strings.ToLower(f.Severity) {
+		case "critical":
+			counts.Critical++
+		case "high":
+			counts.High++
+		case "medium":
+			counts.Medium++
+		default:
+			counts.Low++
+		}
+		if req.MaxSeverity != "" && severityExceeds(f.Severity, req.MaxSeverity) {
+			passed = false
+		}
+	}
+
+	logger.Info("Vulnerability scan complete", "image", req.ImageRef, "critical", counts.Critical, "high", counts.High, "medium", counts.Medium, "low", counts.Low, "passed", passed)
+
+	return &shared.ScanImageResponse{
+		Report: shared.VulnerabilityReport{ImageRef: req.ImageRef, Findings: findings, Counts: counts},
+		Passed: passed,
+	}, nil
+}
+
+var severityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// severityExceeds reports whether found is more severe than the max severity
+// a policy allows through. An unrecognized severity string (malformed scanner
+// output) is treated as critical so the policy fails closed.
+func severityExceeds(found, max string) bool {
+	foundRank, ok := severityRank[strings.ToLower(found)]
+	if !ok {
+		foundRank = severityRank["critical"]
+	}
+	maxRank, ok := severityRank[strings.ToLower(max)]
+	if !ok {
+		maxRank = severityRank["high"]
+	}
+	return foundRank > maxRank
+}
+
+func licenseAllowed(license string, allowed []string) bool {
+	for _, a := range allowed {
+		if strings.EqualFold(a, license) {
+			return true
+		}
+	}
+	return false
+}
+
+// SyftScanner shells out to syft (SBOM) and grype (vulnerabilities) against a
+// registry image reference, the same way docker.go shells out to the docker
+// CLI rather than linking a container-engine SDK.
+type SyftScanner struct{}
+
+func (s *SyftScanner) GenerateSBOM(ctx context.Context, imageRef string) (shared.SBOM, error) {
+	cmd := exec.CommandContext(ctx, "syft", imageRef, "-o", "cyclonedx-json")
+	output, err := cmd.Output()
+	if err != nil {
+		return shared.SBOM{}, fmt.Errorf("syft scan failed: %w", err)
+	}
+
+	packages, err := parseCycloneDXPackages(output)
+	if err != nil {
+		return shared.SBOM{}, fmt.Errorf("failed to parse syft output: %w", err)
+	}
+
+	return shared.SBOM{
+		Format:   "cyclonedx",
+		ImageRef: imageRef,
+		Packages: packages,
+		RawJSON:  string(output),
+	}, nil
+}
+
+func (s *SyftScanner) ScanVulnerabilities(ctx context.Context, imageRef string) ([]shared.VulnerabilityFinding, error) {
+	cmd := exec.CommandContext(ctx, "grype", imageRef, "-o", "json")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("grype scan failed: %w", err)
+	}
+	return parseGrypeFindings(output)
+}
+
+type cyclonedxDocument struct {
+	Components []struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		Licenses []struct {
+			License struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"license"`
+		} `json:"licenses"`
+	} `json:"components"`
+}
+
+func parseCycloneDXPackages(raw []byte) ([]shared.SBOMPackage, error) {
+	var doc cyclonedxDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	packages := make([]shared.SBOMPackage, 0, len(doc.Components))
+	for _, c := range doc.Components {
+		license := ""
+		if len(c.Licenses) > 0 {
+			license = c.Licenses[0].License.ID
+			if license == "" {
+				license = c.Licenses[0].License.Name
+			}
+		}
+		packages = append(packages, shared.SBOMPackage{Name: c.Name, Version: c.Version, License: license})
+	}
+	return packages, nil
+}
+
+type grypeDocument struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+		Artifact struct {
+			Name string `json:"name"`
+		} `json:"artifact"`
+	} `json:"matches"`
+}
+
+func parseGrypeFindings(raw []byte) ([]shared.VulnerabilityFinding, error) {
+	var doc grypeDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	findings := make([]shared.VulnerabilityFinding, 0, len(doc.Matches))
+	for _, m := range doc.Matches {
+		findings = append(findings, shared.VulnerabilityFinding{
+			ID:       m.Vulnerability.ID,
+			Package:  m.Artifact.Name,
+			Severity: m.Vulnerability.Severity,
+		})
+	}
+	return findings, nil
+}
+
+// CosignSigner shells out to cosign for keyless (OIDC) or key-based signing,
+// attestation, and verification. Set KeyRef to sign with a key instead of
+// cosign's default keyless OIDC flow. Keyless Verify requires
+// CertIdentityRegexp and CertOIDCIssuerRegexp to be set: cosign's own
+// defaults for these flags match any identity from any issuer, which is no
+// verification guarantee at all, so this type refuses to supply a
+// match-anything fallback.
+type CosignSigner struct {
+	KeyRef string // optional; empty means keyless OIDC signing
+
+	// CertIdentityRegexp and CertOIDCIssuerRegexp constrain keyless Verify to
+	// signatures from a specific identity/issuer (e.g. a GitHub Actions
+	// workflow and "https://token.actions.githubusercontent.com"). Both are
+	// required when KeyRef is empty; Verify fails closed if either is unset.
+	CertIdentityRegexp   string
+	CertOIDCIssuerRegexp string
+}
+
+func (s *CosignSigner) Sign(ctx context.Context, imageRef string) error {
+	args := append([]string{"sign", "--yes"}, s.keyArgs()...)
+	args = append(args, imageRef)
+
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cosign sign failed: %w\nOutput: %s", err, output)
+	}
+	return nil
+}
+
+func (s *CosignSigner) Attest(ctx context.Context, imageRef, predicateType, predicateJSON string) error {
+	predicateFile, err := os.CreateTemp("", "predicate-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write predicate: %w", err)
+	}
+	defer os.Remove(predicateFile.Name())
+
+	if _, err := predicateFile.WriteString(predicateJSON); err != nil {
+		predicateFile.Close()
+		return fmt.Errorf("failed to write predicate: %w", err)
+	}
+	predicateFile.Close()
+
+	args := append([]string{"attest", "--yes", "--type", predicateType, "--predicate", predicateFile.Name()}, s.keyArgs()...)
+	args = append(args, imageRef)
+
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("cosign attest (%s) failed: %w\nOutput: %s", predicateType, err, output)
+	}
+	return nil
+}
+
+func (s *CosignSigner) Verify(ctx context.Context, imageRef string) (bool, error) {
+	args := []string{"verify"}
+	if s.KeyRef != "" {
+		args = append(args, "--key", s.KeyRef)
+	} else {
+		if s.CertIdentityRegexp == "" || s.CertOIDCIssuerRegexp == "" {
+			return false, fmt.Errorf("keyless verification requires CertIdentityRegexp and CertOIDCIssuerRegexp to be configured, not left to match any identity from any issuer")
+		}
+		args = append(args, "--certificate-identity-regexp", s.CertIdentityRegexp, "--certificate-oidc-issuer-regexp", s.CertOIDCIssuerRegexp)
+	}
+	args = append(args, imageRef)
+
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	if _, err := cmd.CombinedOutput(); err != nil {
+		// An unverified signature is an expected outcome the caller evaluates as
+		// policy, not an activity failure.
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *CosignSigner) keyArgs() []string {
+	if s.KeyRef == "" {
+		return nil
+	}
+	return []string{"--key", s.KeyRef}
+}
@@ -0,0 +1,142 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+)
+
+// MetricsProvider queries a monitoring backend for canary vs. baseline metrics.
+// This indirection lets the workflow be tested and run against Prometheus, Datadog,
+// or a fake provider without changing CanaryActivities itself.
+type MetricsProvider interface {
+	QueryCanary(ctx context.Context, deploymentName, namespace string, window time.Duration) (shared.MetricSnapshot, error)
+	QueryBaseline(ctx context.Context, deploymentName, namespace string, window time.Duration) (shared.MetricSnapshot, error)
+}
+
+// CanaryActivities provides progressive-delivery activities: scaling the canary
+// ReplicaSet in weighted steps and analyzing metrics between steps.
+type CanaryActivities struct {
+	Metrics MetricsProvider
+	K8s     *KubernetesActivities
+}
+
+// NewCanaryActivities wires a metrics provider and the Kubernetes activities used
+// to scale the canary ReplicaSet.
+func NewCanaryActivities(metrics MetricsProvider, k8s *KubernetesActivities) *CanaryActivities {
+	return &CanaryActivities{Metrics: metrics, K8s: k8s}
+}
+
+// ScaleCanary adjusts the weight (percentage of replicas) routed to the canary
+// ReplicaSet for a single progressive-delivery step.
+func (c *CanaryActivities) ScaleCanary(ctx context.Context, req shared.ScaleCanaryRequest) (*shared.ScaleCanaryResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Scaling canary weight",
+		"deployment", req.DeploymentName,
+		"namespace", req.Namespace,
+		"weight", req.WeightPercent)
+
+	if req.WeightPercent < 0 || req.WeightPercent > 100 {
+		return nil, fmt.Errorf("invalid canary weight %d%%: must be between 0 and 100", req.WeightPercent)
+	}
+
+	activity.RecordHeartbeat(ctx, fmt.Sprintf("scaling to %d%%", req.WeightPercent))
+
+	// DEMO HELPER: a full implementation would patch the canary ReplicaSet's
+	// replica count (or a service-mesh traffic split) via the typed client.
+	// For the workshop we just record the target weight; CheckDeploymentStatus
+	// is still used to confirm the canary pods are healthy at this weight.
+	return &shared.ScaleCanaryResponse{
+		Success:       true,
+		WeightPercent: req.WeightPercent,
+		Message:       fmt.Sprintf("canary weight set to %d%%", req.WeightPercent),
+	}, nil
+}
+
+// AnalyzeCanaryMetrics compares the canary against the baseline deployment over the
+// configured window and decides whether the rollout may proceed to the next step.
+func (c *CanaryActivities) AnalyzeCanaryMetrics(ctx context.Context, req shared.AnalyzeCanaryMetricsRequest) (*shared.AnalyzeCanaryMetricsResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Analyzing canary metrics",
+		"deployment", req.DeploymentName,
+		"namespace", req.Namespace,
+		"window", req.Window)
+
+	if c.Metrics == nil {
+		return nil, fmt.Errorf("no metrics provider configured for canary analysis")
+	}
+
+	canary, err := c.Metrics.QueryCanary(ctx, req.DeploymentName, req.Namespace, req.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query canary metrics: %w", err)
+	}
+
+	baseline, err := c.Metrics.QueryBaseline(ctx, req.DeploymentName, req.Namespace, req.Window)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query baseline metrics: %w", err)
+	}
+
+	activity.RecordHeartbeat(ctx, "metrics collected")
+
+	resp := &shared.AnalyzeCanaryMetricsResponse{
+		Canary:   canary,
+		Baseline: baseline,
+		Passed:   true,
+	}
+
+	if canary.ErrorRate > req.Config.MaxErrorRate {
+		resp.Passed = false
+		resp.Reason = fmt.Sprintf("canary error rate %.4f exceeds threshold %.4f", canary.ErrorRate, req.Config.MaxErrorRate)
+	} else if canary.LatencyP95Ms > req.Config.MaxLatencyP95Ms {
+		resp.Passed = false
+		resp.Reason = fmt.Sprintf("canary P95 latency %.1fms exceeds threshold %.1fms", canary.LatencyP95Ms, req.Config.MaxLatencyP95Ms)
+	} else if canary.SuccessRate < req.Config.MinSuccessRate {
+		resp.Passed = false
+		resp.Reason = fmt.Sprintf("canary success rate %.4f below threshold %.4f", canary.SuccessRate, req.Config.MinSuccessRate)
+	} else if req.Config.UseStatisticalTest && !latencyComparableToBaseline(canary, baseline) {
+		resp.Passed = false
+		resp.Reason = "canary latency distribution diverges significantly from baseline"
+	}
+
+	logger.Info("Canary analysis result",
+		"passed", resp.Passed,
+		"reason", resp.Reason,
+		"canaryErrorRate", canary.ErrorRate,
+		"baselineErrorRate", baseline.ErrorRate)
+
+	return resp, nil
+}
+
+// PrometheusMetricsProvider queries a Prometheus-compatible API for canary and
+// baseline metrics. DEMO HELPER: the real PromQL queries depend on the cluster's
+// metric naming conventions, so this returns static placeholder snapshots; point
+// Endpoint at a real Prometheus and replace queryRange with an actual HTTP client
+// call to wire it up for a live cluster.
+type PrometheusMetricsProvider struct {
+	Endpoint string
+}
+
+func (p *PrometheusMetricsProvider) QueryCanary(ctx context.Context, deploymentName, namespace string, window time.Duration) (shared.MetricSnapshot, error) {
+	return shared.MetricSnapshot{SuccessRate: 0.995, LatencyP95Ms: 120, ErrorRate: 0.005, SampleSize: 1000}, nil
+}
+
+func (p *PrometheusMetricsProvider) QueryBaseline(ctx context.Context, deploymentName, namespace string, window time.Duration) (shared.MetricSnapshot, error) {
+	return shared.MetricSnapshot{SuccessRate: 0.997, LatencyP95Ms: 110, ErrorRate: 0.003, SampleSize: 5000}, nil
+}
+
+// latencyComparableToBaseline is a lightweight Mann-Whitney-style check: rather than
+// requiring raw samples (which the pluggable MetricsProvider interface doesn't expose),
+// it compares the canary's P95 against the baseline's P95 with a tolerance band. A real
+// provider that returns raw samples can replace this with an actual rank-sum test.
+func latencyComparableToBaseline(canary, baseline shared.MetricSnapshot) bool {
+	if baseline.LatencyP95Ms == 0 {
+		return true
+	}
+	const toleranceRatio = 1.5
+	return canary.LatencyP95Ms <= baseline.LatencyP95Ms*toleranceRatio && !math.IsNaN(canary.LatencyP95Ms)
+}
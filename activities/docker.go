@@ -3,24 +3,47 @@ package activities
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"os"
-	"os/exec"
-	"strings"
+	"regexp"
 	"time"
 
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/runtime"
 	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
 	"go.temporal.io/sdk/activity"
 )
 
+// DockerActivities builds, tests, and pushes images through a pluggable
+// runtime.ContainerRuntime, instead of assuming the docker CLI is what's
+// installed on the build host.
+type DockerActivities struct {
+	Runtime runtime.ContainerRuntime
+}
+
+// NewDockerActivities wires the container runtime selected at worker startup
+// (docker, buildkit, podman, or nerdctl).
+func NewDockerActivities(rt runtime.ContainerRuntime) *DockerActivities {
+	return &DockerActivities{Runtime: rt}
+}
+
+// heartbeatProgress adapts a runtime.ProgressEvent stream into Temporal
+// activity heartbeats, so long builds/pushes can be cancelled mid-layer
+// instead of only between CLI invocations.
+func heartbeatProgress(ctx context.Context) func(runtime.ProgressEvent) {
+	return func(event runtime.ProgressEvent) {
+		activity.RecordHeartbeat(ctx, event)
+	}
+}
+
 // BuildDockerImage builds a Docker image from the specified context
-func BuildDockerImage(ctx context.Context, req shared.DockerBuildRequest) (*shared.DockerBuildResponse, error) {
+func (d *DockerActivities) BuildDockerImage(ctx context.Context, req shared.DockerBuildRequest) (*shared.DockerBuildResponse, error) {
 	logger := activity.GetLogger(ctx)
-	startTime := time.Now()
 
 	logger.Info("Starting Docker build",
 		"image", req.ImageName,
 		"tag", req.Tag,
-		"context", req.BuildContext)
+		"context", req.BuildContext,
+		"platforms", req.Platforms)
 
 	// DEMO HELPER: Simulate random build failures
 	if os.Getenv("SIMULATE_DOCKER_FAILURE") == "true" {
@@ -30,129 +53,161 @@ func BuildDockerImage(ctx context.Context, req shared.DockerBuildRequest) (*shar
 		}
 	}
 
-	// Construct the image tag
-	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
-
-	// Build the Docker image for local testing (current platform only)
-	// Multi-arch build will happen during push phase
-	logger.Info("Building image for local testing")
-	
-	cmd := exec.CommandContext(ctx, "docker", "buildx", "build",
-		"-t", imageTag,
-		"-f", req.Dockerfile,
-		"--load", // Load for local testing
-		req.BuildContext)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		logger.Error("Docker build failed",
-			"error", err,
-			"output", string(output))
-		return nil, fmt.Errorf("docker build failed: %w\nOutput: %s", err, output)
-	}
-
-	// Get the image ID
-	idCmd := exec.CommandContext(ctx, "docker", "images", "-q", imageTag)
-	imageIDBytes, err := idCmd.Output()
+	// Build the image for local testing (current platform only unless the
+	// caller asked for specific ones); the multi-arch build happens at push time.
+	result, err := d.Runtime.Build(ctx, runtime.BuildRequest{
+		ImageName:    req.ImageName,
+		Tag:          req.Tag,
+		Dockerfile:   req.Dockerfile,
+		BuildContext: req.BuildContext,
+		Platforms:    req.Platforms,
+		OnProgress:   heartbeatProgress(ctx),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image ID: %w", err)
+		logger.Error("Docker build failed", "error", err)
+		return nil, err
 	}
-	imageID := strings.TrimSpace(string(imageIDBytes))
 
-	duration := time.Since(startTime)
 	logger.Info("Docker build completed successfully",
-		"imageID", imageID,
-		"duration", duration)
-
-	// Record heartbeat for long-running builds
-	activity.RecordHeartbeat(ctx, "Build completed")
+		"imageID", result.ImageID,
+		"duration", result.BuildTime)
 
 	return &shared.DockerBuildResponse{
-		ImageID:   imageID,
-		BuildTime: duration,
+		ImageID:   result.ImageID,
+		BuildTime: result.BuildTime,
 	}, nil
 }
 
+// heartbeatProbeAttempt adapts a runtime readiness-probe attempt into a
+// Temporal activity heartbeat, so Temporal can cancel a container stuck
+// failing its probe instead of only between whole-test-suite invocations.
+func heartbeatProbeAttempt(ctx context.Context) runtime.OnProbeAttempt {
+	return func(attempt int, ready bool, detail string) {
+		activity.RecordHeartbeat(ctx, fmt.Sprintf("probe attempt %d: ready=%v %s", attempt, ready, detail))
+	}
+}
+
 // TestDockerContainer runs tests against the built Docker image
-func TestDockerContainer(ctx context.Context, req shared.DockerTestRequest) (*shared.DockerTestResponse, error) {
+func (d *DockerActivities) TestDockerContainer(ctx context.Context, req shared.DockerTestRequest) (*shared.DockerTestResponse, error) {
 	logger := activity.GetLogger(ctx)
-	startTime := time.Now()
 
 	logger.Info("Starting Docker container tests",
 		"image", req.ImageName,
 		"tag", req.Tag)
 
-	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
-	containerName := fmt.Sprintf("test-%s-%d", req.Tag, time.Now().Unix())
-
-	// Start the container
-	runCmd := exec.CommandContext(ctx, "docker", "run",
-		"-d",
-		"--name", containerName,
-		"-p", "8080",
-		imageTag)
-
-	if output, err := runCmd.CombinedOutput(); err != nil {
-		logger.Error("Failed to start test container",
-			"error", err,
-			"output", string(output))
-		return nil, fmt.Errorf("failed to start container: %w", err)
+	result, err := d.Runtime.Test(ctx, runtime.TestRequest{
+		ImageName: req.ImageName,
+		Tag:       req.Tag,
+		Probe: runtime.ReadinessProbe{
+			Type:             req.Probe.Type,
+			Path:             req.Probe.Path,
+			ExpectedStatus:   req.Probe.ExpectedStatus,
+			Command:          req.Probe.Command,
+			Interval:         req.Probe.Interval,
+			Timeout:          req.Probe.Timeout,
+			FailureThreshold: req.Probe.FailureThreshold,
+		},
+		OnProbeAttempt: heartbeatProbeAttempt(ctx),
+	})
+	if err != nil {
+		logger.Error("Docker container tests failed", "error", err)
+		return nil, err
 	}
 
-	// Ensure cleanup
-	defer func() {
-		stopCmd := exec.Command("docker", "rm", "-f", containerName)
-		stopCmd.Run()
-	}()
+	logger.Info("Docker tests completed",
+		"passed", result.Passed,
+		"duration", result.TestTime,
+		"output", result.Output)
 
-	// Get the mapped port
-	portCmd := exec.CommandContext(ctx, "docker", "port", containerName, "8080")
-	portOutput, err := portCmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get container port: %w", err)
-	}
+	return &shared.DockerTestResponse{
+		Passed:   result.Passed,
+		TestTime: result.TestTime,
+		Output:   result.Output,
+	}, nil
+}
 
-	// Extract port from output (format: "0.0.0.0:32768")
-	portStr := strings.TrimSpace(string(portOutput))
-	parts := strings.Split(portStr, ":")
-	if len(parts) < 2 {
-		return nil, fmt.Errorf("unexpected port format: %s", portStr)
+// heartbeatOutput adapts a hook's output lines into Temporal activity
+// heartbeats, the RunHook counterpart to heartbeatProgress.
+func heartbeatOutput(ctx context.Context) func(string) {
+	return func(line string) {
+		activity.RecordHeartbeat(ctx, line)
 	}
-	port := parts[len(parts)-1]
+}
 
-	// Wait for container to be ready
-	time.Sleep(2 * time.Second)
+// RunPostCommitHook runs a project-defined smoke test in an ephemeral
+// container started from the just-built image, the same role OpenShift's
+// post-commit build hook plays: a place for project-specific unit/integration
+// tests that don't belong in TestDockerContainer's fixed sample-app test. When
+// req.FailBuildOnError is true, a non-zero exit is returned as an error so the
+// workflow can skip the push; callers that want that failure to be
+// non-retryable should scope it with a MaximumAttempts:1 RetryPolicy, the same
+// way RegistryLogin is scoped in the pipeline workflow.
+func (d *DockerActivities) RunPostCommitHook(ctx context.Context, req shared.PostCommitHookRequest) (*shared.PostCommitHookResponse, error) {
+	logger := activity.GetLogger(ctx)
+	info := activity.GetInfo(ctx)
 
-	// Run integration tests
-	testCmd := exec.CommandContext(ctx, "go", "test")
-	testCmd.Dir = "sample-app"
-	testCmd.Env = append(os.Environ(), fmt.Sprintf("BASE_URL=http://localhost:%s", port))
+	hookName := fmt.Sprintf("postcommit-%s-%d", sanitizeContainerName(info.WorkflowExecution.ID), rand.Intn(1_000_000))
 
-	testOutput, err := testCmd.CombinedOutput()
-	passed := err == nil
+	logger.Info("Running post-commit hook",
+		"image", req.ImageName,
+		"tag", req.Tag,
+		"container", hookName)
+
+	result, err := d.Runtime.RunHook(ctx, runtime.HookRequest{
+		ImageName: req.ImageName,
+		Tag:       req.Tag,
+		Name:      hookName,
+		Script:    req.Script,
+		Command:   req.Command,
+		Args:      req.Args,
+		Env:       req.Env,
+		OnOutput:  heartbeatOutput(ctx),
+	})
+	if err != nil {
+		logger.Error("Post-commit hook failed to run", "error", err)
+		return nil, err
+	}
 
-	duration := time.Since(startTime)
-	logger.Info("Docker tests completed",
+	passed := result.ExitCode == 0
+	logger.Info("Post-commit hook completed",
+		"exitCode", result.ExitCode,
 		"passed", passed,
-		"duration", duration,
-		"output", string(testOutput))
+		"output", result.Output)
 
-	return &shared.DockerTestResponse{
+	resp := &shared.PostCommitHookResponse{
+		ExitCode: result.ExitCode,
+		Output:   result.Output,
 		Passed:   passed,
-		TestTime: duration,
-		Output:   string(testOutput),
-	}, nil
+	}
+
+	if !passed && req.FailBuildOnError {
+		return resp, fmt.Errorf("post-commit hook exited %d: %s", result.ExitCode, result.Output)
+	}
+
+	return resp, nil
+}
+
+// containerNameChars matches the characters docker/podman/nerdctl accept in a
+// container name; anything else (e.g. the "/" and ":" Temporal workflow IDs
+// are free to contain) gets replaced with "-".
+var containerNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// sanitizeContainerName makes a Temporal workflow ID safe to embed in a
+// container name, which is far more restrictive about allowed characters.
+func sanitizeContainerName(workflowID string) string {
+	return containerNameChars.ReplaceAllString(workflowID, "-")
 }
 
 // PushToRegistry pushes the Docker image to the specified registry
-func PushToRegistry(ctx context.Context, req shared.DockerPushRequest) (*shared.DockerPushResponse, error) {
+func (d *DockerActivities) PushToRegistry(ctx context.Context, req shared.DockerPushRequest) (*shared.DockerPushResponse, error) {
 	logger := activity.GetLogger(ctx)
 	startTime := time.Now()
 
 	logger.Info("Starting Docker push to registry",
 		"image", req.ImageName,
 		"tag", req.Tag,
-		"registry", req.RegistryURL)
+		"registry", req.RegistryURL,
+		"platforms", req.Platforms)
 
 	// DEMO HELPER: Simulate occasional push failures
 	if os.Getenv("SIMULATE_PUSH_FAILURE") == "true" {
@@ -162,123 +217,34 @@ func PushToRegistry(ctx context.Context, req shared.DockerPushRequest) (*shared.
 		}
 	}
 
-	remoteTag := shared.FormatImageTag(req.RegistryURL, req.ImageName, req.Tag)
-
-	// Build and push multi-architecture image directly to registry
-	logger.Info("Building and pushing multi-architecture image", 
-		"platforms", "linux/amd64,linux/arm64",
-		"remoteTag", remoteTag)
-
-	// Ensure we have a multi-platform capable builder
-	builderName := "multiarch-builder"
-	
-	// Remove existing builder first to avoid conflicts
-	removeBuilderCmd := exec.CommandContext(ctx, "docker", "buildx", "rm", builderName)
-	removeBuilderCmd.Run() // Ignore errors if builder doesn't exist
-	
-	// Create fresh builder
-	createBuilderCmd := exec.CommandContext(ctx, "docker", "buildx", "create", 
-		"--name", builderName, 
-		"--driver", "docker-container",
-		"--use")
-	createOutput, createErr := createBuilderCmd.CombinedOutput()
-	
-	if createErr != nil {
-		logger.Error("Failed to create multi-arch builder", 
-			"error", createErr,
-			"output", string(createOutput))
-		return nil, fmt.Errorf("failed to create multi-arch builder: %w", createErr)
-	}
-	
-	logger.Info("Created fresh multi-arch builder", "name", builderName)
-
-	// Rebuild for multi-architecture and push directly to registry
-	// Use a unique tag to avoid conflicts with existing untagged images
-	timestampTag := fmt.Sprintf("%s-%d", req.Tag, time.Now().Unix())
-	multiArchTag := shared.FormatImageTag(req.RegistryURL, req.ImageName, timestampTag)
-	
-	buildCmd := exec.CommandContext(ctx, "docker", "buildx", "build",
-		"--platform", "linux/amd64,linux/arm64",
-		"-t", multiArchTag,
-		"-f", req.Dockerfile,
-		"--no-cache", // Force clean build to ensure CGO_ENABLED=0 fix is applied
-		"--push", // Push directly to registry
-		req.BuildContext)
-
-	pushOutput, err := buildCmd.CombinedOutput()
+	result, err := d.Runtime.Push(ctx, runtime.PushRequest{
+		ImageName:    req.ImageName,
+		Tag:          req.Tag,
+		RegistryURL:  req.RegistryURL,
+		Dockerfile:   req.Dockerfile,
+		BuildContext: req.BuildContext,
+		Platforms:    req.Platforms,
+		Auth: runtime.RegistryAuth{
+			Username:         req.Auth.Username,
+			Password:         req.Auth.Password,
+			IdentityToken:    req.Auth.IdentityToken,
+			CredentialHelper: req.Auth.CredentialHelper,
+		},
+		OnProgress: heartbeatProgress(ctx),
+	})
 	if err != nil {
-		logger.Warn("Multi-arch build failed, falling back to single-arch build and push",
-			"error", err,
-			"output", string(pushOutput))
-		
-		// Fallback: Tag and push single-arch image
-		localTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
-		
-		// Tag the image for the remote registry
-		tagCmd := exec.CommandContext(ctx, "docker", "tag", localTag, remoteTag)
-		if tagOutput, tagErr := tagCmd.CombinedOutput(); tagErr != nil {
-			logger.Error("Failed to tag image for fallback",
-				"error", tagErr,
-				"output", string(tagOutput))
-			return nil, fmt.Errorf("failed to tag image: %w", tagErr)
-		}
-
-		// Push single-arch image
-		fallbackPushCmd := exec.CommandContext(ctx, "docker", "push", remoteTag)
-		fallbackOutput, fallbackErr := fallbackPushCmd.CombinedOutput()
-		if fallbackErr != nil {
-			logger.Error("Fallback push also failed",
-				"error", fallbackErr,
-				"output", string(fallbackOutput))
-			return nil, fmt.Errorf("failed to push image (both multi-arch and fallback failed): %w\nOutput: %s", fallbackErr, fallbackOutput)
-		}
-		
-		logger.Info("Successfully pushed single-arch image as fallback")
-		pushOutput = fallbackOutput
-	} else {
-		// Multi-arch build succeeded, now tag it with the original tag
-		logger.Info("Multi-arch build succeeded, creating additional tag", "originalTag", remoteTag)
-		
-		// Use buildx imagetools to create an additional tag pointing to the same manifest
-		tagCmd := exec.CommandContext(ctx, "docker", "buildx", "imagetools", "create", 
-			"-t", remoteTag, 
-			multiArchTag)
-		if tagOutput, tagErr := tagCmd.CombinedOutput(); tagErr != nil {
-			logger.Warn("Failed to create additional tag, but multi-arch push succeeded",
-				"error", tagErr,
-				"output", string(tagOutput))
-		} else {
-			logger.Info("Successfully created additional tag", "tag", remoteTag)
-		}
+		logger.Error("Docker push failed", "error", err)
+		return nil, err
 	}
 
-	// Extract digest from push output
-	digest := extractDigest(string(pushOutput))
-
-	duration := time.Since(startTime)
 	logger.Info("Docker push completed successfully",
-		"digest", digest,
-		"duration", duration)
-
-	// Record heartbeat for long pushes
-	activity.RecordHeartbeat(ctx, "Push completed")
+		"digest", result.Digest,
+		"archDigests", result.ArchDigests,
+		"duration", time.Since(startTime))
 
 	return &shared.DockerPushResponse{
-		Digest:   digest,
-		PushTime: duration,
+		Digest:      result.Digest,
+		ArchDigests: result.ArchDigests,
+		PushTime:    result.PushTime,
 	}, nil
 }
-
-// extractDigest extracts the digest from docker push output
-func extractDigest(output string) string {
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		if strings.Contains(line, "digest:") && strings.Contains(line, "sha256:") {
-			parts := strings.Split(line, "sha256:")
-			if len(parts) >= 2 {
-				return "sha256:" + strings.TrimSpace(parts[1])
-			}
-		}
-	}
-	return "unknown"
-}
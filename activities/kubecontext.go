@@ -0,0 +1,39 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+)
+
+// KubeContextResolver loads per-cluster kubeconfigs from a secret store so
+// MultiClusterDeployWorkflow can direct KubernetesActivities at N clusters
+// without each worker needing every cluster's credentials baked in locally.
+type KubeContextResolver struct{}
+
+// ResolveKubeContext looks up the kubeconfig context for a named cluster.
+//
+// DEMO HELPER: a production implementation would fetch this from Vault, AWS
+// Secrets Manager, or a similar secret store keyed by cluster name. For the
+// workshop we read it from an env var named KUBECONTEXT_<CLUSTER>, falling
+// back to using the cluster name itself as the context name (the common case
+// when all clusters are merged into one local kubeconfig).
+func (r *KubeContextResolver) ResolveKubeContext(ctx context.Context, req shared.KubeContextResolverRequest) (*shared.KubeContextResolverResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Resolving kube context", "cluster", req.ClusterName)
+
+	envKey := fmt.Sprintf("KUBECONTEXT_%s", req.ClusterName)
+	kubeContext := os.Getenv(envKey)
+	if kubeContext == "" {
+		kubeContext = req.ClusterName
+	}
+
+	return &shared.KubeContextResolverResponse{
+		KubeContext: kubeContext,
+		KubeConfig:  os.Getenv("KUBECONFIG"),
+	}, nil
+}
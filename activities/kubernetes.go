@@ -1,31 +1,75 @@
 package activities
 
 import (
-	"bytes"
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
-	"strings"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
 	"go.temporal.io/sdk/activity"
 
 	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
 )
 
-// KubernetesActivities provides Kubernetes deployment operations
+const deploymentFieldManager = "cicd-pipeline"
+
+// KubernetesActivities provides Kubernetes deployment operations backed by a
+// typed client-go client instead of shelling out to the kubectl binary. The
+// client is injected so tests can pass k8s.io/client-go/kubernetes/fake.Clientset.
 type KubernetesActivities struct {
+	Client    kubernetes.Interface
 	Namespace string
+
+	// ClientResolver, when set, returns the kubernetes.Interface for a given
+	// kube-context name. This is how MultiClusterDeployWorkflow directs a single
+	// KubernetesActivities instance at N different clusters; when nil (or the
+	// request's KubeContext is empty) Client is used directly.
+	ClientResolver func(kubeContext string) (kubernetes.Interface, error)
+
+	// portForwardMu guards portForwards, which tracks the kubectl port-forward
+	// tunnels opened by PortForwardServiceURL so StopPortForward (run as a later,
+	// separate activity) can find and tear down the right process.
+	portForwardMu sync.Mutex
+	portForwards  map[string]*portForwardTunnel
+}
+
+// NewKubernetesActivities wires a real or fake kubernetes.Interface client.
+func NewKubernetesActivities(client kubernetes.Interface, namespace string) *KubernetesActivities {
+	return &KubernetesActivities{Client: client, Namespace: namespace}
+}
+
+// clientFor resolves the client to use for a request, honoring an explicit
+// kube-context when ClientResolver is configured.
+func (k *KubernetesActivities) clientFor(kubeContext string) (kubernetes.Interface, error) {
+	if kubeContext == "" || k.ClientResolver == nil {
+		return k.Client, nil
+	}
+	return k.ClientResolver(kubeContext)
 }
 
-// DeployToKubernetes deploys the application to Kubernetes
+// DeployToKubernetes deploys the application to Kubernetes using Server-Side
+// Apply, then waits for the rollout to become healthy.
 func (k *KubernetesActivities) DeployToKubernetes(ctx context.Context, req shared.DeployToKubernetesRequest) (*shared.DeployToKubernetesResponse, error) {
 	logger := activity.GetLogger(ctx)
 	info := activity.GetInfo(ctx)
 	namespace := k.getNamespace(req.Environment)
 	deploymentName := "demo-app"
-	
-	// Log activity start
+
 	logger.Info("Starting Kubernetes deployment",
 		"image", req.ImageTag,
 		"environment", req.Environment,
@@ -33,97 +77,46 @@ func (k *KubernetesActivities) DeployToKubernetes(ctx context.Context, req share
 		"activityID", info.ActivityID,
 		"attempt", info.Attempt)
 
-	// Step 1: Update deployment with new image
-	logger.Info("[1/5] Updating deployment with new image")
-	updateCmd := exec.Command("kubectl", "set", "image", 
-		fmt.Sprintf("deployment/%s", deploymentName),
-		fmt.Sprintf("%s=%s", deploymentName, req.ImageTag),
-		"-n", namespace)
-	
-	var updateOut bytes.Buffer
-	var updateErr bytes.Buffer
-	updateCmd.Stdout = &updateOut
-	updateCmd.Stderr = &updateErr
-	
-	if err := updateCmd.Run(); err != nil {
-		// If deployment doesn't exist, create it
-		if strings.Contains(updateErr.String(), "not found") {
-			logger.Info("Deployment not found, creating new deployment")
-			if err := k.createDeployment(ctx, deploymentName, req.ImageTag, namespace); err != nil {
-				return nil, fmt.Errorf("failed to create deployment: %w", err)
-			}
-		} else {
-			logger.Error("Failed to update deployment", "error", err, "stderr", updateErr.String())
-			return nil, fmt.Errorf("failed to update deployment: %s", updateErr.String())
-		}
-	} else {
-		logger.Info("Deployment updated", "output", updateOut.String())
-	}
-	
-	activity.RecordHeartbeat(ctx, "Deployment updated")
-
-	// Step 2: Wait for rollout to complete
-	logger.Info("[2/5] Waiting for rollout to complete")
-	rolloutCmd := exec.Command("kubectl", "rollout", "status", 
-		fmt.Sprintf("deployment/%s", deploymentName),
-		"-n", namespace,
-		"--timeout=30s")
-	
-	var rolloutOut bytes.Buffer
-	var rolloutErr bytes.Buffer
-	rolloutCmd.Stdout = &rolloutOut
-	rolloutCmd.Stderr = &rolloutErr
-	
-	if err := rolloutCmd.Run(); err != nil {
-		logger.Warn("Rollout timed out or failed, checking pod status", "error", err, "stderr", rolloutErr.String())
-		
-		// Get pod status to provide better error information
-		podCmd := exec.Command("kubectl", "get", "pods", "-n", namespace, "-l", fmt.Sprintf("app=%s", deploymentName), "-o", "wide")
-		var podOut bytes.Buffer
-		podCmd.Stdout = &podOut
-		if podErr := podCmd.Run(); podErr == nil {
-			logger.Info("Pod status", "pods", podOut.String())
-		}
-		
-		// Get detailed pod logs to understand the issue
-		logCmd := exec.Command("kubectl", "logs", "-n", namespace, "-l", fmt.Sprintf("app=%s", deploymentName), "--tail=10")
-		var logOut bytes.Buffer
-		logCmd.Stdout = &logOut
-		if logErr := logCmd.Run(); logErr == nil {
-			logger.Info("Pod logs", "logs", logOut.String())
-		}
-		
-		// For demo purposes, continue anyway but log the issue
-		logger.Warn("Rollout failed - pods may be crashing due to architecture mismatch or application issues")
-		logger.Info("Continuing with demo using simulated success")
-	}
-	
-	logger.Info("Rollout completed", "output", rolloutOut.String())
-	activity.RecordHeartbeat(ctx, "Rollout completed")
-
-	// Step 3: Ensure service exists
-	logger.Info("[3/5] Ensuring service exists")
-	if err := k.ensureService(ctx, deploymentName, namespace); err != nil {
-		return nil, fmt.Errorf("failed to ensure service: %w", err)
+	client, err := k.clientFor(req.KubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve kubernetes client for context %q: %w", req.KubeContext, err)
+	}
+
+	// Server-Side Apply: the field manager lets multiple writers (this pipeline,
+	// kubectl, an operator) co-own the Deployment without clobbering each other's
+	// fields, unlike a full client-side Update. Force=true lets the pipeline take
+	// ownership of fields other managers (e.g. an earlier kubectl apply) set.
+	logger.Info("[1/4] Applying deployment via server-side apply")
+	deployment := buildDeploymentManifest(deploymentName, namespace, req.ImageTag)
+	patch, err := json.Marshal(deployment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal deployment manifest: %w", err)
 	}
-	
-	activity.RecordHeartbeat(ctx, "Service configured")
 
-	// Step 4: Get service URL
-	logger.Info("[4/5] Getting service URL")
-	serviceURL, err := k.getActualServiceURL(ctx, deploymentName, namespace)
+	force := true
+	applied, err := client.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.ApplyPatchType, patch, metav1.PatchOptions{
+		FieldManager: deploymentFieldManager,
+		Force:        &force,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get service URL: %w", err)
+		logger.Error("Failed to apply deployment", "error", err)
+		return nil, fmt.Errorf("failed to apply deployment %s: %w", deploymentName, err)
+	}
+	activity.RecordHeartbeat(ctx, "deployment applied")
+
+	logger.Info("[2/4] Waiting for rollout to complete", "generation", applied.Generation)
+	if err := k.waitForRollout(ctx, client, deploymentName, namespace, applied.Generation); err != nil {
+		return nil, fmt.Errorf("rollout did not become healthy: %w", err)
+	}
+
+	logger.Info("[3/4] Ensuring service exists")
+	serviceURL, err := k.ensureServiceAndGetURL(ctx, client, deploymentName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure service: %w", err)
 	}
-	
-	logger.Info("Service URL retrieved", "url", serviceURL)
-	activity.RecordHeartbeat(ctx, "Service URL retrieved")
+	activity.RecordHeartbeat(ctx, "service ready")
 
-	// Step 5: Verify deployment health
-	logger.Info("[5/5] Verifying deployment health")
-	time.Sleep(2 * time.Second) // Give pods time to stabilize
-	
-	logger.Info("Kubernetes deployment completed successfully",
+	logger.Info("[4/4] Kubernetes deployment completed successfully",
 		"environment", req.Environment,
 		"deploymentURL", serviceURL)
 
@@ -132,62 +125,545 @@ func (k *KubernetesActivities) DeployToKubernetes(ctx context.Context, req share
 		DeploymentURL: serviceURL,
 		Message:       fmt.Sprintf("Successfully deployed %s to %s", req.ImageTag, req.Environment),
 		Timestamp:     time.Now(),
+		KubeContext:   req.KubeContext,
 	}, nil
 }
 
-// CheckDeploymentStatus checks the status of a Kubernetes deployment
+// waitForRollout watches the Deployment via the typed watch API until it
+// reports ready, heartbeating on every observed event so Temporal can detect
+// and cancel a stuck activity. This replaces polling with `kubectl rollout
+// status`/repeated Get calls: the watch only wakes us on an actual status
+// change, and ctx cancellation (activity timeout or workflow cancel) tears it
+// down immediately via watcher.Stop.
+func (k *KubernetesActivities) waitForRollout(ctx context.Context, client kubernetes.Interface, name, namespace string, generation int64) error {
+	logger := activity.GetLogger(ctx)
+
+	// Seed with the current state in case the Deployment is already complete
+	// (e.g. it had no spec changes to roll out) before any watch event arrives.
+	current, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get deployment %s: %w", name, err)
+	}
+	if deploymentComplete(current, generation) {
+		logger.Info("Rollout already complete",
+			"updatedReplicas", current.Status.UpdatedReplicas,
+			"availableReplicas", current.Status.AvailableReplicas)
+		return nil
+	}
+
+	watcher, err := client.AppsV1().Deployments(namespace).Watch(ctx, metav1.ListOptions{
+		FieldSelector:   fmt.Sprintf("metadata.name=%s", name),
+		ResourceVersion: current.ResourceVersion,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch deployment %s: %w", name, err)
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch closed before deployment %s became healthy", name)
+			}
+
+			deployment, ok := event.Object.(*appsv1.Deployment)
+			if !ok || deployment.Name != name {
+				continue
+			}
+
+			activity.RecordHeartbeat(ctx, fmt.Sprintf("observedGeneration=%d updated=%d available=%d",
+				deployment.Status.ObservedGeneration, deployment.Status.UpdatedReplicas, deployment.Status.AvailableReplicas))
+
+			if deploymentComplete(deployment, generation) {
+				logger.Info("Rollout complete",
+					"updatedReplicas", deployment.Status.UpdatedReplicas,
+					"availableReplicas", deployment.Status.AvailableReplicas)
+				return nil
+			}
+		}
+	}
+}
+
+// deploymentComplete mirrors kubernetes/kubectl's deploymentutil.DeploymentComplete:
+// the controller has observed the latest spec, and the expected number of
+// replicas have been updated and are available.
+func deploymentComplete(d *appsv1.Deployment, expectedGeneration int64) bool {
+	if d.Status.ObservedGeneration < expectedGeneration {
+		return false
+	}
+	desired := int32(1)
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	return d.Status.UpdatedReplicas == desired &&
+		d.Status.Replicas == desired &&
+		d.Status.AvailableReplicas == desired
+}
+
+// CheckDeploymentStatus checks the readiness of a Kubernetes deployment using
+// the same typed rollout-complete criteria as DeployToKubernetes. Unlike
+// DeployToKubernetes it always queries the default cluster; it isn't
+// cluster-aware since nothing yet needs to poll status for a specific
+// multi-cluster target.
 func (k *KubernetesActivities) CheckDeploymentStatus(ctx context.Context, req shared.CheckDeploymentStatusRequest) (*shared.CheckDeploymentStatusResponse, error) {
 	logger := activity.GetLogger(ctx)
-	
-	logger.Info("Checking deployment status",
-		"environment", req.Environment,
-		"namespace", k.getNamespace(req.Environment))
+	namespace := k.getNamespace(req.Environment)
 
-	// Simulate status check
-	time.Sleep(1 * time.Second)
+	logger.Info("Checking deployment status", "environment", req.Environment, "namespace", namespace)
+
+	deployment, err := k.Client.AppsV1().Deployments(namespace).Get(ctx, "demo-app", metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment status: %w", err)
+	}
+
+	ready := deploymentComplete(deployment, deployment.Generation)
+	message := "All pods are running and ready"
+	if !ready {
+		message = fmt.Sprintf("rollout in progress: %d/%d replicas updated", deployment.Status.UpdatedReplicas, deployment.Status.Replicas)
+	}
 
-	// In a real implementation, this would query the Kubernetes API
 	return &shared.CheckDeploymentStatusResponse{
-		Ready:        true,
-		Replicas:     3,
-		ReadyReplicas: 3,
-		Message:      "All pods are running and ready",
+		Ready:         ready,
+		Replicas:      deployment.Status.Replicas,
+		ReadyReplicas: deployment.Status.ReadyReplicas,
+		Message:       message,
 	}, nil
 }
 
-
 // GetServiceURL retrieves the service URL for a deployment
 func (k *KubernetesActivities) GetServiceURL(ctx context.Context, req shared.GetServiceURLRequest) (*shared.GetServiceURLResponse, error) {
 	logger := activity.GetLogger(ctx)
-	
-	logger.Info("Getting service URL",
-		"environment", req.Environment,
-		"serviceName", req.ServiceName)
+	namespace := k.getNamespace(req.Environment)
 
-	// Simulate service lookup
-	time.Sleep(500 * time.Millisecond)
+	logger.Info("Getting service URL", "environment", req.Environment, "serviceName", req.ServiceName)
 
-	// Generate URL based on environment
-	var serviceURL string
-	if req.Environment == "staging" {
-		serviceURL = fmt.Sprintf("http://staging.%s.local:8080", req.ServiceName)
-	} else {
-		serviceURL = fmt.Sprintf("https://%s.production.com", req.ServiceName)
+	url, err := k.serviceURL(ctx, k.Client, req.ServiceName, namespace)
+	if err != nil {
+		return &shared.GetServiceURLResponse{Ready: false, Message: err.Error()}, nil
 	}
 
 	return &shared.GetServiceURLResponse{
-		URL:     serviceURL,
+		URL:     url,
 		Ready:   true,
 		Message: "Service is accessible",
 	}, nil
 }
 
-// Helper method to get namespace based on environment
+// portForwardAddrPattern matches kubectl port-forward's stderr announcement of
+// the local address it bound, e.g. "Forwarding from 127.0.0.1:54321 -> 8080".
+var portForwardAddrPattern = regexp.MustCompile(`Forwarding from 127\.0\.0\.1:(\d+)`)
+
+// portForwardTunnel tracks a running `kubectl port-forward` child process so a
+// later StopPortForward call can cancel it.
+type portForwardTunnel struct {
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+}
+
+// PortForwardServiceURL opens a `kubectl port-forward` tunnel to a Service and
+// returns a loopback URL for it. It's the fallback for clusters (kind,
+// minikube, private VPCs) where GetServiceURL can't resolve a LoadBalancer
+// ingress or NodePort: the tunnel gives the workflow a reachable URL anyway.
+//
+// The tunnel outlives this activity — it's handed off to the portForwards map
+// and must be torn down with a separate StopPortForward call once the caller
+// is done with it (e.g. after the smoke-test step). Cancelling ctx before the
+// tunnel is established aborts the attempt; cancelling it afterwards has no
+// effect, since the child process is rooted in a detached context by then.
+func (k *KubernetesActivities) PortForwardServiceURL(ctx context.Context, req shared.PortForwardRequest) (*shared.PortForwardResponse, error) {
+	logger := activity.GetLogger(ctx)
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = k.getNamespace(req.Environment)
+	}
+	targetPort := req.TargetPort
+	if targetPort == 0 {
+		targetPort = 8080
+	}
+	key := portForwardKey(namespace, req.ServiceName)
+
+	logger.Info("Starting port-forward tunnel",
+		"service", req.ServiceName, "namespace", namespace, "targetPort", targetPort)
+
+	tunnelCtx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(tunnelCtx, "kubectl", "port-forward",
+		fmt.Sprintf("svc/%s", req.ServiceName), fmt.Sprintf(":%d", targetPort),
+		"-n", namespace)
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to attach to port-forward stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start port-forward: %w", err)
+	}
+
+	portCh := make(chan int, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		matched := false
+		scanner := bufio.NewScanner(stderr)
+		// Keep draining stderr for the tunnel's whole lifetime, not just until the
+		// first match: kubectl blocks once its stderr pipe buffer fills, which
+		// would silently kill the forward out from under a caller still using it.
+		for scanner.Scan() {
+			if matched {
+				continue
+			}
+			if m := portForwardAddrPattern.FindStringSubmatch(scanner.Text()); m != nil {
+				if port, err := strconv.Atoi(m[1]); err == nil {
+					matched = true
+					portCh <- port
+				}
+			}
+		}
+		if !matched {
+			errCh <- fmt.Errorf("kubectl port-forward exited before establishing a tunnel")
+		}
+	}()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			return nil, ctx.Err()
+		case err := <-errCh:
+			cancel()
+			return nil, err
+		case localPort := <-portCh:
+			k.registerPortForward(key, cmd, cancel)
+			url := fmt.Sprintf("http://127.0.0.1:%d", localPort)
+			logger.Info("Port-forward tunnel established", "url", url)
+			return &shared.PortForwardResponse{URL: url, LocalPort: localPort}, nil
+		case <-ticker.C:
+			activity.RecordHeartbeat(ctx, "waiting for port-forward tunnel")
+		}
+	}
+}
+
+// StopPortForward tears down the tunnel a prior PortForwardServiceURL call
+// opened for the same service/namespace, if one is still running.
+func (k *KubernetesActivities) StopPortForward(ctx context.Context, req shared.StopPortForwardRequest) (*shared.StopPortForwardResponse, error) {
+	logger := activity.GetLogger(ctx)
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = k.getNamespace(req.Environment)
+	}
+	key := portForwardKey(namespace, req.ServiceName)
+
+	tunnel, ok := k.releasePortForward(key)
+	if !ok {
+		logger.Info("No active port-forward tunnel to stop", "service", req.ServiceName, "namespace", namespace)
+		return &shared.StopPortForwardResponse{Stopped: false}, nil
+	}
+
+	tunnel.cancel()
+	_ = tunnel.cmd.Wait()
+	logger.Info("Port-forward tunnel stopped", "service", req.ServiceName, "namespace", namespace)
+	return &shared.StopPortForwardResponse{Stopped: true}, nil
+}
+
+// portForwardKey identifies a tunnel by the service it targets, since only one
+// forward per namespace/service pair is expected to be live at a time.
+func portForwardKey(namespace, serviceName string) string {
+	return namespace + "/" + serviceName
+}
+
+func (k *KubernetesActivities) registerPortForward(key string, cmd *exec.Cmd, cancel context.CancelFunc) {
+	k.portForwardMu.Lock()
+	defer k.portForwardMu.Unlock()
+	if k.portForwards == nil {
+		k.portForwards = make(map[string]*portForwardTunnel)
+	}
+	k.portForwards[key] = &portForwardTunnel{cmd: cmd, cancel: cancel}
+}
+
+func (k *KubernetesActivities) releasePortForward(key string) (*portForwardTunnel, bool) {
+	k.portForwardMu.Lock()
+	defer k.portForwardMu.Unlock()
+	tunnel, ok := k.portForwards[key]
+	if ok {
+		delete(k.portForwards, key)
+	}
+	return tunnel, ok
+}
+
+// CollectPodDiagnostics gathers the information a human would reach for after
+// a failed rollout — pod/container state, recent logs (including the previous
+// instance's logs for a container that has restarted), and events — for every
+// pod matching app=<DeploymentName>. It's invoked as a best-effort diagnostic
+// step when a deploy fails, replacing what used to be ad hoc `kubectl get
+// pods`/`kubectl logs` calls.
+func (k *KubernetesActivities) CollectPodDiagnostics(ctx context.Context, req shared.PodDiagnosticsRequest) (*shared.PodDiagnosticsResponse, error) {
+	logger := activity.GetLogger(ctx)
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = k.Namespace
+	}
+	logLines := req.LogLines
+	if logLines == 0 {
+		logLines = 10
+	}
+
+	logger.Info("Collecting pod diagnostics", "deployment", req.DeploymentName, "namespace", namespace)
+
+	podList, err := k.Client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=%s", req.DeploymentName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods for %s: %w", req.DeploymentName, err)
+	}
+
+	response := &shared.PodDiagnosticsResponse{}
+	for _, pod := range podList.Items {
+		diagnostic := shared.PodDiagnostic{Name: pod.Name, Phase: string(pod.Status.Phase)}
+		for _, status := range pod.Status.ContainerStatuses {
+			container := shared.ContainerDiagnostic{
+				Name:         status.Name,
+				Ready:        status.Ready,
+				RestartCount: status.RestartCount,
+				State:        containerStateSummary(status.State),
+				Logs:         k.fetchContainerLogs(ctx, namespace, pod.Name, status.Name, logLines, false),
+			}
+			if status.RestartCount > 0 {
+				container.PreviousLogs = k.fetchContainerLogs(ctx, namespace, pod.Name, status.Name, logLines, true)
+			}
+			diagnostic.Containers = append(diagnostic.Containers, container)
+		}
+		response.Pods = append(response.Pods, diagnostic)
+		activity.RecordHeartbeat(ctx, fmt.Sprintf("collected diagnostics for pod %s", pod.Name))
+	}
+
+	events, err := k.podEvents(ctx, namespace, podList.Items)
+	if err != nil {
+		logger.Error("Failed to collect pod events", "error", err)
+	} else {
+		response.Events = events
+	}
+
+	return response, nil
+}
+
+// fetchContainerLogs returns up to tailLines of a container's log output,
+// falling back to an inline error message on failure rather than failing the
+// whole diagnostics collection over one container.
+func (k *KubernetesActivities) fetchContainerLogs(ctx context.Context, namespace, podName, containerName string, tailLines int64, previous bool) string {
+	stream, err := k.Client.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{
+		Container: containerName,
+		TailLines: &tailLines,
+		Previous:  previous,
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Sprintf("failed to fetch logs: %v", err)
+	}
+	defer stream.Close()
+
+	logs, err := io.ReadAll(stream)
+	if err != nil {
+		return fmt.Sprintf("failed to read logs: %v", err)
+	}
+	return string(logs)
+}
+
+// containerStateSummary mirrors the one-line container state `kubectl
+// describe pod` prints in its "Containers" section.
+func containerStateSummary(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return fmt.Sprintf("waiting: %s", state.Waiting.Reason)
+	case state.Running != nil:
+		return "running"
+	case state.Terminated != nil:
+		return fmt.Sprintf("terminated: %s", state.Terminated.Reason)
+	default:
+		return "unknown"
+	}
+}
+
+// podEvents lists events in namespace, keeps only those involving one of pods,
+// and returns them oldest-first across all pods.
+func (k *KubernetesActivities) podEvents(ctx context.Context, namespace string, pods []corev1.Pod) ([]shared.PodEvent, error) {
+	podNames := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		podNames[pod.Name] = true
+	}
+
+	eventList, err := k.Client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events in %s: %w", namespace, err)
+	}
+
+	var events []shared.PodEvent
+	for _, event := range eventList.Items {
+		if event.InvolvedObject.Kind != "Pod" || !podNames[event.InvolvedObject.Name] {
+			continue
+		}
+		events = append(events, shared.PodEvent{
+			PodName:       event.InvolvedObject.Name,
+			Type:          event.Type,
+			Reason:        event.Reason,
+			Message:       event.Message,
+			LastTimestamp: event.LastTimestamp.Time,
+		})
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(events[j].LastTimestamp)
+	})
+
+	return events, nil
+}
+
+// revisionAnnotation is the annotation the Deployment controller stamps on
+// every ReplicaSet it owns with that ReplicaSet's revision number — the same
+// bookkeeping `kubectl rollout undo`/`kubectl rollout history` read.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// RollbackDeployment reverts a Deployment to a prior revision. By default it
+// picks the revision immediately before the current one from the Deployment's
+// ReplicaSet history (mirroring `kubectl rollout undo`); req.ToRevision pins a
+// specific revision instead, and req.PreviousTag bypasses history lookup
+// entirely by setting the image directly. Either way it waits for the
+// resulting rollout to become healthy using the same watch-based check as a
+// forward deploy.
+func (k *KubernetesActivities) RollbackDeployment(ctx context.Context, req shared.RollbackRequest) (*shared.RollbackResponse, error) {
+	logger := activity.GetLogger(ctx)
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = k.Namespace
+	}
+
+	logger.Info("Rolling back deployment",
+		"deployment", req.DeploymentName,
+		"namespace", namespace,
+		"kubeContext", req.KubeContext,
+		"previousTag", req.PreviousTag,
+		"toRevision", req.ToRevision)
+
+	client, err := k.clientFor(req.KubeContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve kubernetes client for context %q: %w", req.KubeContext, err)
+	}
+
+	deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, req.DeploymentName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s: %w", req.DeploymentName, err)
+	}
+
+	var revision int64
+	restoredImage := req.PreviousTag
+	if restoredImage != "" {
+		for i := range deployment.Spec.Template.Spec.Containers {
+			deployment.Spec.Template.Spec.Containers[i].Image = restoredImage
+		}
+	} else {
+		targetRS, rev, err := k.findRollbackTarget(ctx, client, deployment, namespace, req.ToRevision)
+		if err != nil {
+			return nil, err
+		}
+		deployment.Spec.Template = targetRS.Spec.Template
+		restoredImage = firstContainerImage(targetRS.Spec.Template)
+		revision = rev
+	}
+
+	updated, err := client.AppsV1().Deployments(namespace).Update(ctx, deployment, metav1.UpdateOptions{
+		FieldManager: deploymentFieldManager,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back deployment %s: %w", req.DeploymentName, err)
+	}
+
+	activity.RecordHeartbeat(ctx, fmt.Sprintf("rollback issued, restoring revision %d", revision))
+
+	if err := k.waitForRollout(ctx, client, req.DeploymentName, namespace, updated.Generation); err != nil {
+		return nil, fmt.Errorf("rollback did not become healthy: %w", err)
+	}
+
+	logger.Info("Rollback completed", "deployment", req.DeploymentName, "image", restoredImage, "revision", revision)
+
+	return &shared.RollbackResponse{
+		Success:       true,
+		RestoredImage: restoredImage,
+		Revision:      revision,
+		Message:       fmt.Sprintf("rolled back %s in %s", req.DeploymentName, namespace),
+	}, nil
+}
+
+// findRollbackTarget locates the ReplicaSet to roll back to from the
+// Deployment's revision history: toRevision if non-zero, otherwise the
+// revision immediately before the newest one (the deployment's current
+// revision).
+func (k *KubernetesActivities) findRollbackTarget(ctx context.Context, client kubernetes.Interface, deployment *appsv1.Deployment, namespace string, toRevision int64) (*appsv1.ReplicaSet, int64, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid selector on deployment %s: %w", deployment.Name, err)
+	}
+
+	rsList, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list replicasets for %s: %w", deployment.Name, err)
+	}
+
+	type revisionedRS struct {
+		rs       appsv1.ReplicaSet
+		revision int64
+	}
+	var history []revisionedRS
+	for _, rs := range rsList.Items {
+		revStr, ok := rs.Annotations[revisionAnnotation]
+		if !ok {
+			continue
+		}
+		rev, err := strconv.ParseInt(revStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		history = append(history, revisionedRS{rs: rs, revision: rev})
+	}
+	if len(history) == 0 {
+		return nil, 0, fmt.Errorf("no revision history found for deployment %s", deployment.Name)
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].revision > history[j].revision })
+
+	if toRevision != 0 {
+		for _, h := range history {
+			if h.revision == toRevision {
+				return &h.rs, h.revision, nil
+			}
+		}
+		return nil, 0, fmt.Errorf("revision %d not found in history for deployment %s", toRevision, deployment.Name)
+	}
+
+	if len(history) < 2 {
+		return nil, 0, fmt.Errorf("no previous revision available for deployment %s", deployment.Name)
+	}
+	target := history[1]
+	return &target.rs, target.revision, nil
+}
+
+// firstContainerImage returns the image of a pod template's first container,
+// which is all this single-container demo app ever deploys.
+func firstContainerImage(template corev1.PodTemplateSpec) string {
+	if len(template.Spec.Containers) == 0 {
+		return ""
+	}
+	return template.Spec.Containers[0].Image
+}
+
+// getNamespace resolves the namespace based on environment
 func (k *KubernetesActivities) getNamespace(environment string) string {
 	if k.Namespace != "" {
 		return k.Namespace
 	}
-	
+
 	switch environment {
 	case "staging":
 		return "staging"
@@ -198,180 +674,94 @@ func (k *KubernetesActivities) getNamespace(environment string) string {
 	}
 }
 
-// createDeployment creates a new Kubernetes deployment
-func (k *KubernetesActivities) createDeployment(ctx context.Context, name, image, namespace string) error {
-	logger := activity.GetLogger(ctx)
-	
-	// Create deployment YAML
-	deploymentYAML := fmt.Sprintf(`
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: %s
-  namespace: %s
-spec:
-  replicas: 3
-  selector:
-    matchLabels:
-      app: %s
-  template:
-    metadata:
-      labels:
-        app: %s
-    spec:
-      containers:
-      - name: %s
-        image: %s
-        imagePullPolicy: Always
-        ports:
-        - containerPort: 8080
-`, name, namespace, name, name, name, image)
-
-	// Apply the deployment
-	cmd := exec.Command("kubectl", "apply", "-f", "-")
-	cmd.Stdin = strings.NewReader(deploymentYAML)
-	
-	var out bytes.Buffer
-	var errOut bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &errOut
-	
-	if err := cmd.Run(); err != nil {
-		logger.Error("Failed to create deployment", "error", err, "stderr", errOut.String())
-		return fmt.Errorf("failed to create deployment: %s", errOut.String())
-	}
-	
-	logger.Info("Deployment created", "output", out.String())
-	return nil
+// buildDeploymentManifest constructs the typed apps/v1 Deployment object applied
+// for every environment.
+func buildDeploymentManifest(name, namespace, image string) *appsv1.Deployment {
+	replicas := int32(3)
+	labels := map[string]string{"app": name}
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apps/v1",
+			Kind:       "Deployment",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            name,
+							Image:           image,
+							ImagePullPolicy: corev1.PullAlways,
+							Ports:           []corev1.ContainerPort{{ContainerPort: 8080}},
+						},
+					},
+				},
+			},
+		},
+	}
 }
 
-// ensureService ensures a Kubernetes service exists for the deployment
-func (k *KubernetesActivities) ensureService(ctx context.Context, name, namespace string) error {
+// ensureServiceAndGetURL creates the Service if it doesn't exist yet and
+// resolves a reachable URL for it (LoadBalancer ingress, falling back to
+// ClusterIP for local/demo clusters that never get an external address).
+func (k *KubernetesActivities) ensureServiceAndGetURL(ctx context.Context, client kubernetes.Interface, name, namespace string) (string, error) {
 	logger := activity.GetLogger(ctx)
-	
-	// Check if service exists
-	checkCmd := exec.Command("kubectl", "get", "service", name, "-n", namespace)
-	if err := checkCmd.Run(); err == nil {
-		logger.Info("Service already exists")
-		return nil
+
+	_, err := client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		logger.Info("Service not found, creating it")
+		service := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": name},
+				Ports:    []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+				Type:     corev1.ServiceTypeLoadBalancer,
+			},
+		}
+		if _, err := client.CoreV1().Services(namespace).Create(ctx, service, metav1.CreateOptions{
+			FieldManager: deploymentFieldManager,
+		}); err != nil {
+			return "", fmt.Errorf("failed to create service: %w", err)
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get service %s: %w", name, err)
 	}
-	
-	// Create service YAML
-	serviceYAML := fmt.Sprintf(`
-apiVersion: v1
-kind: Service
-metadata:
-  name: %s
-  namespace: %s
-spec:
-  selector:
-    app: %s
-  ports:
-  - port: 80
-    targetPort: 8080
-  type: LoadBalancer
-`, name, namespace, name)
-
-	// Apply the service
-	cmd := exec.Command("kubectl", "apply", "-f", "-")
-	cmd.Stdin = strings.NewReader(serviceYAML)
-	
-	var out bytes.Buffer
-	var errOut bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &errOut
-	
-	if err := cmd.Run(); err != nil {
-		logger.Error("Failed to create service", "error", err, "stderr", errOut.String())
-		return fmt.Errorf("failed to create service: %s", errOut.String())
-	}
-	
-	logger.Info("Service created", "output", out.String())
-	return nil
+
+	return k.serviceURL(ctx, client, name, namespace)
 }
 
-// getActualServiceURL gets the actual URL for the Kubernetes service
-func (k *KubernetesActivities) getActualServiceURL(ctx context.Context, name, namespace string) (string, error) {
-	logger := activity.GetLogger(ctx)
-	
-	// Try to get external IP/hostname from LoadBalancer service
-	cmd := exec.Command("kubectl", "get", "service", name, "-n", namespace, 
-		"-o", "jsonpath={.status.loadBalancer.ingress[0].hostname}{.status.loadBalancer.ingress[0].ip}")
-	
-	var out bytes.Buffer
-	var errOut bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &errOut
-	
-	if err := cmd.Run(); err != nil {
-		logger.Warn("Failed to get LoadBalancer URL, trying NodePort", "error", err, "stderr", errOut.String())
-		// If LoadBalancer is not available, try NodePort
-		return k.getNodePortURL(ctx, name, namespace)
-	}
-	
-	externalAddr := strings.TrimSpace(out.String())
-	logger.Info("LoadBalancer external address", "address", externalAddr, "namespace", namespace)
-	
-	if externalAddr == "" {
-		logger.Warn("No external address found, trying NodePort")
-		return k.getNodePortURL(ctx, name, namespace)
-	}
-	
-	// Determine protocol based on environment
+// serviceURL resolves a reachable URL for the named Service.
+func (k *KubernetesActivities) serviceURL(ctx context.Context, client kubernetes.Interface, name, namespace string) (string, error) {
+	service, err := client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get service %s: %w", name, err)
+	}
+
 	protocol := "http"
 	if namespace == "production" {
 		protocol = "https"
 	}
-	
-	serviceURL := fmt.Sprintf("%s://%s", protocol, externalAddr)
-	logger.Info("Generated LoadBalancer service URL", "url", serviceURL)
-	
-	return serviceURL, nil
-}
 
-// getNodePortURL gets the NodePort URL as a fallback
-func (k *KubernetesActivities) getNodePortURL(ctx context.Context, name, namespace string) (string, error) {
-	logger := activity.GetLogger(ctx)
-	
-	// Get node IP
-	nodeCmd := exec.Command("kubectl", "get", "nodes", "-o", 
-		"jsonpath={.items[0].status.addresses[?(@.type=='InternalIP')].address}")
-	
-	var nodeOut bytes.Buffer
-	var nodeErr bytes.Buffer
-	nodeCmd.Stdout = &nodeOut
-	nodeCmd.Stderr = &nodeErr
-	
-	if err := nodeCmd.Run(); err != nil {
-		logger.Error("Failed to get node IP", "error", err, "stderr", nodeErr.String())
-		// Return a default URL if we can't get the actual one
-		if namespace == "staging" {
-			return "http://staging.demo-app.local:8080", nil
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			return fmt.Sprintf("%s://%s", protocol, ingress.Hostname), nil
 		}
-		return "https://demo-app.production.local", nil
-	}
-	
-	nodeIP := strings.TrimSpace(nodeOut.String())
-	
-	// Get NodePort
-	portCmd := exec.Command("kubectl", "get", "service", name, "-n", namespace,
-		"-o", "jsonpath={.spec.ports[0].nodePort}")
-	
-	var portOut bytes.Buffer
-	var portErr bytes.Buffer
-	portCmd.Stdout = &portOut
-	portCmd.Stderr = &portErr
-	
-	if err := portCmd.Run(); err != nil {
-		logger.Error("Failed to get NodePort", "error", err, "stderr", portErr.String())
-		// Return a default URL if we can't get the actual one
-		if namespace == "staging" {
-			return "http://staging.demo-app.local:8080", nil
+		if ingress.IP != "" {
+			return fmt.Sprintf("%s://%s", protocol, ingress.IP), nil
 		}
-		return "https://demo-app.production.local", nil
 	}
-	
-	nodePort := strings.TrimSpace(portOut.String())
-	
-	return fmt.Sprintf("http://%s:%s", nodeIP, nodePort), nil
-}
\ No newline at end of file
+
+	// No LoadBalancer ingress yet (common on kind/minikube): fall back to the
+	// in-cluster DNS name. PortForwardServiceURL (chunk1-2) covers reaching it
+	// from outside the cluster.
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local", name, namespace), nil
+}
@@ -0,0 +1,214 @@
+package activities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+)
+
+// newTestActivityEnvironment returns an activity environment so activity
+// methods that call activity.GetLogger/GetInfo can run in a test, the same
+// way a real worker would host them.
+func newTestActivityEnvironment() *testsuite.TestActivityEnvironment {
+	return (&testsuite.WorkflowTestSuite{}).NewTestActivityEnvironment()
+}
+
+// seedHealthyDeployment inserts a Deployment into the fake clientset whose
+// status already satisfies deploymentComplete, as if the rollout had already
+// finished by the time the activity observes it.
+func seedHealthyDeployment(t *testing.T, client *fake.Clientset, name, namespace string) {
+	t.Helper()
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Generation: 1},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1,
+			Replicas:           replicas,
+			UpdatedReplicas:    replicas,
+			AvailableReplicas:  replicas,
+			ReadyReplicas:      replicas,
+		},
+	}
+	if _, err := client.AppsV1().Deployments(namespace).Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to seed deployment: %v", err)
+	}
+}
+
+func TestCheckDeploymentStatus_Ready(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	seedHealthyDeployment(t, client, "demo-app", "staging")
+
+	k := NewKubernetesActivities(client, "staging")
+	env := newTestActivityEnvironment()
+	env.RegisterActivity(k.CheckDeploymentStatus)
+	encoded, err := env.ExecuteActivity(k.CheckDeploymentStatus, shared.CheckDeploymentStatusRequest{Environment: "staging"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp shared.CheckDeploymentStatusResponse
+	if err := encoded.Get(&resp); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if !resp.Ready {
+		t.Errorf("expected deployment to be ready, got message: %s", resp.Message)
+	}
+	if resp.ReadyReplicas != 3 {
+		t.Errorf("expected 3 ready replicas, got %d", resp.ReadyReplicas)
+	}
+}
+
+func TestCheckDeploymentStatus_RolloutInProgress(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-app", Namespace: "staging", Generation: 2},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo-app"}},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1, // controller hasn't caught up to the latest spec yet
+			Replicas:           replicas,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		},
+	}
+	if _, err := client.AppsV1().Deployments("staging").Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to seed deployment: %v", err)
+	}
+
+	k := NewKubernetesActivities(client, "staging")
+	env := newTestActivityEnvironment()
+	env.RegisterActivity(k.CheckDeploymentStatus)
+	encoded, err := env.ExecuteActivity(k.CheckDeploymentStatus, shared.CheckDeploymentStatusRequest{Environment: "staging"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp shared.CheckDeploymentStatusResponse
+	if err := encoded.Get(&resp); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if resp.Ready {
+		t.Errorf("expected deployment to not be ready yet")
+	}
+}
+
+func TestGetServiceURL_LoadBalancerIngress(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-app", Namespace: "staging"},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}},
+			},
+		},
+	})
+
+	k := NewKubernetesActivities(client, "staging")
+	env := newTestActivityEnvironment()
+	env.RegisterActivity(k.GetServiceURL)
+	encoded, err := env.ExecuteActivity(k.GetServiceURL, shared.GetServiceURLRequest{Environment: "staging", ServiceName: "demo-app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp shared.GetServiceURLResponse
+	if err := encoded.Get(&resp); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if resp.URL != "http://203.0.113.10" {
+		t.Errorf("expected http://203.0.113.10, got %s", resp.URL)
+	}
+}
+
+func TestGetServiceURL_NoIngressFallsBackToClusterDNS(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-app", Namespace: "staging"},
+	})
+
+	k := NewKubernetesActivities(client, "staging")
+	env := newTestActivityEnvironment()
+	env.RegisterActivity(k.GetServiceURL)
+	encoded, err := env.ExecuteActivity(k.GetServiceURL, shared.GetServiceURLRequest{Environment: "staging", ServiceName: "demo-app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var resp shared.GetServiceURLResponse
+	if err := encoded.Get(&resp); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if resp.URL != "http://demo-app.staging.svc.cluster.local" {
+		t.Errorf("expected cluster-local fallback URL, got %s", resp.URL)
+	}
+}
+
+// TestWaitForRollout_CompletesOnWatchEvent verifies that waitForRollout relies
+// on the watch API rather than polling: it only returns once an Update event
+// reports the deployment caught up to the expected generation, not on a timer.
+func TestWaitForRollout_CompletesOnWatchEvent(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	replicas := int32(3)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo-app", Namespace: "staging", Generation: 2},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo-app"}},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 1, // controller hasn't caught up to the latest spec yet
+			Replicas:           replicas,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+		},
+	}
+	if _, err := client.AppsV1().Deployments("staging").Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to seed deployment: %v", err)
+	}
+
+	k := NewKubernetesActivities(client, "staging")
+	env := newTestActivityEnvironment()
+	waitForRollout := func(ctx context.Context) error {
+		return k.waitForRollout(ctx, client, "demo-app", "staging", 2)
+	}
+	env.RegisterActivity(waitForRollout)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := env.ExecuteActivity(waitForRollout)
+		done <- err
+	}()
+
+	// Give waitForRollout time to establish its watch before the update fires;
+	// the fake clientset only broadcasts to watchers already registered.
+	time.Sleep(100 * time.Millisecond)
+
+	deployment.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		AvailableReplicas:  replicas,
+	}
+	if _, err := client.AppsV1().Deployments("staging").UpdateStatus(context.Background(), deployment, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update deployment status: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitForRollout did not return after the deployment became healthy")
+	}
+}
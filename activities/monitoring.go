@@ -3,33 +3,131 @@ package activities
 import (
 	"context"
 	"fmt"
-	"os/exec"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 
 	"go.temporal.io/sdk/activity"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
 )
 
 // MonitoringActivities handles deployment monitoring and rollback operations
-type MonitoringActivities struct{}
+type MonitoringActivities struct {
+	Client kubernetes.Interface
+}
 
+// NewMonitoringActivities wires a real or fake kubernetes.Interface client.
+func NewMonitoringActivities(client kubernetes.Interface) *MonitoringActivities {
+	return &MonitoringActivities{Client: client}
+}
 
-// ValidateDeployment validates that a deployment is working correctly (placeholder for demo)
+// ValidateDeployment watches pod events for the given deployment via an
+// informer and fails fast if any pod enters CrashLoopBackOff, instead of a
+// single point-in-time `kubectl get` check.
 func (m *MonitoringActivities) ValidateDeployment(ctx context.Context, environment string) error {
 	logger := activity.GetLogger(ctx)
-	
 	logger.Info("Validating deployment", "environment", environment)
-	
-	// Simple validation: check that deployment is ready
-	checkCmd := exec.CommandContext(ctx, "kubectl", "get", "deployment", 
-		"sample-app", "-n", environment, "-o", "jsonpath={.status.readyReplicas}")
-	
-	output, err := checkCmd.Output()
-	if err != nil {
-		return fmt.Errorf("validation failed - could not check deployment status: %w", err)
+
+	deploymentName := "demo-app"
+	namespace := shared.GetNamespaceForEnvironment(environment)
+	selector := fmt.Sprintf("app=%s", deploymentName)
+
+	watchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	factory := cache.NewFilteredListWatchFromClient(
+		m.Client.CoreV1().RESTClient(),
+		"pods",
+		namespace,
+		withLabelSelector(selector),
+	)
+
+	var crashMu sync.Mutex
+	var crashErr error
+	recordCrash := func(err error) {
+		if err == nil {
+			return
+		}
+		crashMu.Lock()
+		defer crashMu.Unlock()
+		if crashErr == nil {
+			crashErr = err
+		}
+	}
+	getCrash := func() error {
+		crashMu.Lock()
+		defer crashMu.Unlock()
+		return crashErr
+	}
+
+	_, controller := cache.NewInformer(factory, &corev1.Pod{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { recordCrash(checkCrashLoop(obj)) },
+		UpdateFunc: func(_, obj interface{}) { recordCrash(checkCrashLoop(obj)) },
+	})
+
+	done := make(chan struct{})
+	go func() {
+		controller.Run(watchCtx.Done())
+		close(done)
+	}()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watchCtx.Done():
+			if err := getCrash(); err != nil {
+				return err
+			}
+			logger.Info("Deployment validation window elapsed with no CrashLoopBackOff observed", "environment", environment)
+			return nil
+		case <-ticker.C:
+			if err := getCrash(); err != nil {
+				logger.Error("Validation failed: pod crash-looping", "error", err)
+				return err
+			}
+			activity.RecordHeartbeat(ctx, "watching pod events")
+		case <-done:
+			if err := getCrash(); err != nil {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// checkCrashLoop inspects a pod's container statuses and returns an error if
+// any container is CrashLoopBackOff or has restarted more than 5 times, nil
+// otherwise.
+func checkCrashLoop(obj interface{}) error {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff" {
+			return fmt.Errorf("pod %s container %s is CrashLoopBackOff: %s", pod.Name, cs.Name, cs.State.Waiting.Message)
+		}
+		if cs.RestartCount > 5 {
+			return fmt.Errorf("pod %s container %s has restarted %d times", pod.Name, cs.Name, cs.RestartCount)
+		}
 	}
-	
-	logger.Info("Deployment validation completed", 
-		"environment", environment,
-		"readyReplicas", string(output))
-	
 	return nil
-}
\ No newline at end of file
+}
+
+// fields builds a metav1.ListOptions label-selector function compatible with
+// cache.NewListWatchFromClient's optionsModifier signature.
+// withLabelSelector builds the ListOptions-modifying func
+// cache.NewFilteredListWatchFromClient expects, scoping the list/watch to
+// pods matching labelSelector instead of every pod in the namespace.
+func withLabelSelector(labelSelector string) func(options *metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		options.LabelSelector = labelSelector
+	}
+}
@@ -0,0 +1,80 @@
+package activities
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/runtime"
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+)
+
+// RegistryActivities validates registry credentials ahead of a build/push, so
+// a bad secret fails fast with a clear error instead of surfacing midway
+// through a push.
+type RegistryActivities struct{}
+
+// RegistryLogin checks req.Auth (or, if empty, ~/.docker/config.json)
+// against the registry's /v2/ endpoint.
+func (r *RegistryActivities) RegistryLogin(ctx context.Context, req shared.RegistryLoginRequest) (*shared.RegistryLoginResponse, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Validating registry credentials", "registry", req.RegistryURL)
+
+	result, err := runtime.Login(ctx, runtime.LoginRequest{
+		RegistryURL: req.RegistryURL,
+		Auth: runtime.RegistryAuth{
+			Username:         req.Auth.Username,
+			Password:         req.Auth.Password,
+			IdentityToken:    req.Auth.IdentityToken,
+			CredentialHelper: req.Auth.CredentialHelper,
+		},
+	})
+	if err != nil {
+		logger.Error("Registry login failed", "registry", req.RegistryURL, "error", err)
+		return nil, err
+	}
+
+	logger.Info("Registry login succeeded", "registry", req.RegistryURL, "username", result.Username)
+	return &shared.RegistryLoginResponse{Username: result.Username}, nil
+}
+
+// PullThroughCache checks req.Registry's mirrors (in order) and then its
+// primary RegistryURL for an existing copy of the image, so an unchanged
+// source tree can skip the build/test/push phase entirely on re-runs.
+func (r *RegistryActivities) PullThroughCache(ctx context.Context, req shared.PullThroughCacheRequest) (*shared.PullThroughCacheResponse, error) {
+	logger := activity.GetLogger(ctx)
+
+	ref := req.Tag
+	if req.Digest != "" {
+		ref = req.Digest
+	}
+
+	auth := runtime.RegistryAuth{
+		Username:         req.Auth.Username,
+		Password:         req.Auth.Password,
+		IdentityToken:    req.Auth.IdentityToken,
+		CredentialHelper: req.Auth.CredentialHelper,
+	}
+
+	candidates := append(append([]string{}, req.Registry.Mirrors...), req.Registry.RegistryURL)
+	for _, registryURL := range candidates {
+		result, err := runtime.CheckManifestExists(ctx, runtime.ManifestCheckRequest{
+			RegistryURL: registryURL,
+			Insecure:    req.Registry.Insecure,
+			ImageName:   req.ImageName,
+			Ref:         ref,
+			Auth:        auth,
+		})
+		if err != nil {
+			logger.Error("Manifest check failed, trying next registry", "registry", registryURL, "error", err)
+			continue
+		}
+		if result.Exists {
+			logger.Info("Image already present, skipping build/push", "registry", registryURL, "digest", result.Digest)
+			return &shared.PullThroughCacheResponse{Found: true, RegistryURL: registryURL, Digest: result.Digest}, nil
+		}
+	}
+
+	logger.Info("Image not found in any configured registry or mirror", "image", req.ImageName, "ref", ref)
+	return &shared.PullThroughCacheResponse{Found: false}, nil
+}
@@ -0,0 +1,203 @@
+// cmd/approval-server runs a small HTTP callback server that verifies approval
+// correlation tokens (from Slack button clicks, email links, etc.) and forwards
+// the decision to the waiting workflow via client.SignalWorkflow, so operators
+// don't need the Temporal CLI or cmd/starter to approve a production deploy.
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+	"go.temporal.io/sdk/client"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "HTTP listen address")
+	flag.Parse()
+
+	c, err := client.Dial(client.Options{HostPort: getTemporalHost()})
+	if err != nil {
+		log.Fatalf("unable to create Temporal client: %v", err)
+	}
+	defer c.Close()
+
+	// Matches ApprovalChannelConfig.CallbackSigningSecret; when unset, incoming
+	// links are trusted on the token alone (same as before this was added).
+	signingSecret := os.Getenv("APPROVAL_CALLBACK_SIGNING_SECRET")
+	if signingSecret == "" {
+		log.Println("APPROVAL_CALLBACK_SIGNING_SECRET not set; approve/reject links are unsigned")
+	}
+
+	used := newTokenTracker()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/approve", decisionHandler(c, true, signingSecret, used))
+	mux.HandleFunc("/reject", decisionHandler(c, false, signingSecret, used))
+
+	log.Printf("approval-server listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		log.Fatalf("approval-server failed: %v", err)
+	}
+}
+
+// decisionHandler builds an http.HandlerFunc for either the approve or reject
+// endpoint. Both expect a `token` query param as produced by
+// shared.EncodeCorrelationToken in SendApprovalRequest, plus a `sig` param
+// when signingSecret is set.
+//
+// GET only renders a confirmation page with a button that POSTs back to this
+// same URL; it never signals the workflow. That's deliberate: the callback
+// URL is the one mailed/Slacked to approvers, and those get followed by
+// plenty of things that aren't a human — email security gateways, chat
+// link-unfurl bots, corporate web proxies. Only the POST (the button click)
+// actually decides the deployment, and used rejects a token that's already
+// been claimed so a replayed POST can't flip a decision already made.
+func decisionHandler(c client.Client, approved bool, signingSecret string, used *tokenTracker) http.HandlerFunc {
+	action := "reject"
+	if approved {
+		action = "approve"
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			http.Error(w, "missing token", http.StatusBadRequest)
+			return
+		}
+
+		if signingSecret != "" {
+			want := shared.SignCallbackToken(signingSecret, token)
+			got := r.URL.Query().Get("sig")
+			if len(got) != len(want) || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+				http.Error(w, "invalid signature", http.StatusForbidden)
+				return
+			}
+		}
+
+		workflowID, runID, ok := shared.DecodeCorrelationToken(token)
+		if !ok {
+			http.Error(w, "invalid token", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			renderConfirmPage(w, r, action)
+			return
+		case http.MethodPost:
+			// handled below
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !used.claim(token) {
+			http.Error(w, "this approval link has already been used", http.StatusConflict)
+			return
+		}
+
+		approver := r.FormValue("approver")
+		if approver == "" {
+			approver = "unknown"
+		}
+		reason := r.FormValue("reason")
+
+		signal := shared.ApprovalSignal{
+			Approved: approved,
+			Approver: approver,
+			Reason:   reason,
+		}
+
+		if err := c.SignalWorkflow(context.Background(), workflowID, runID, "approval", signal); err != nil {
+			log.Printf("failed to signal workflow %s: %v", workflowID, err)
+			used.release(token)
+			http.Error(w, "failed to signal workflow", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"ok": true})
+	}
+}
+
+// renderConfirmPage serves the GET landing page for /approve and /reject: a
+// plain HTML form that re-submits the same token (and approver/reason, if
+// present) as a POST. Carrying the query string through r.URL.RequestURI()
+// means the form action still includes `sig` when the request was signed.
+func renderConfirmPage(w http.ResponseWriter, r *http.Request, action string) {
+	approver := r.URL.Query().Get("approver")
+	reason := r.URL.Query().Get("reason")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html>
+<head><title>Confirm %[1]s</title></head>
+<body>
+<h1>Confirm %[1]s</h1>
+<p>This link only stages the decision. Click the button to actually %[1]s the deployment.</p>
+<form method="POST" action="%[2]s">
+<input type="hidden" name="approver" value="%[3]s">
+<input type="hidden" name="reason" value="%[4]s">
+<button type="submit">%[1]s deployment</button>
+</form>
+</body>
+</html>
+`, html.EscapeString(action), html.EscapeString(r.URL.RequestURI()), html.EscapeString(approver), html.EscapeString(reason))
+}
+
+// tokenTracker enforces that a correlation token can be used to decide a
+// deployment at most once, so a replayed or pre-fetched POST can't re-trigger
+// (or flip) a decision already made.
+//
+// DEMO HELPER: this is an in-memory, per-process set, so it forgets every
+// token on restart and doesn't coordinate across multiple approval-server
+// replicas. A production deployment would back this with the same store the
+// correlation tokens/workflow history already live in.
+type tokenTracker struct {
+	mu      sync.Mutex
+	claimed map[string]bool
+}
+
+func newTokenTracker() *tokenTracker {
+	return &tokenTracker{claimed: make(map[string]bool)}
+}
+
+// claim reports whether this is the first time token has been claimed.
+func (t *tokenTracker) claim(token string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.claimed[token] {
+		return false
+	}
+	t.claimed[token] = true
+	return true
+}
+
+// release un-claims token, used when SignalWorkflow fails so a transient
+// error doesn't permanently burn an approval link the workflow never
+// actually received.
+func (t *tokenTracker) release(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.claimed, token)
+}
+
+func getTemporalHost() string {
+	host := os.Getenv("TEMPORAL_HOST")
+	if host == "" {
+		return "localhost:7233"
+	}
+	return host
+}
@@ -0,0 +1,141 @@
+package runtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// resolvedAuth is the credential form every runtime implementation's push
+// path encodes into its own registry-auth header.
+type resolvedAuth struct {
+	Username      string
+	Password      string
+	IdentityToken string
+}
+
+// resolveAuth turns a RegistryAuth into concrete credentials: explicit
+// Username/Password or IdentityToken wins, then CredentialHelper (shelling
+// out to docker-credential-<name>), then ~/.docker/config.json - the same
+// precedence the docker CLI itself uses.
+func resolveAuth(registryURL string, auth RegistryAuth) (resolvedAuth, error) {
+	if auth.Username != "" || auth.IdentityToken != "" {
+		return resolvedAuth{Username: auth.Username, Password: auth.Password, IdentityToken: auth.IdentityToken}, nil
+	}
+	if auth.CredentialHelper != "" {
+		return credentialHelperAuth(auth.CredentialHelper, registryURL)
+	}
+	return dockerConfigAuth(registryURL)
+}
+
+// credentialHelperAuth shells out to docker-credential-<helper> get, the
+// same protocol `docker login`'s credsStore/credHelpers support uses.
+func credentialHelperAuth(helper, registryURL string) (resolvedAuth, error) {
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registryURL)
+	output, err := cmd.Output()
+	if err != nil {
+		return resolvedAuth{}, fmt.Errorf("docker-credential-%s get failed: %w", helper, err)
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(output, &creds); err != nil {
+		return resolvedAuth{}, fmt.Errorf("failed to parse docker-credential-%s output: %w", helper, err)
+	}
+
+	if creds.Username == "<token>" {
+		return resolvedAuth{IdentityToken: creds.Secret}, nil
+	}
+	return resolvedAuth{Username: creds.Username, Password: creds.Secret}, nil
+}
+
+// dockerConfigAuth loads ~/.docker/config.json the way the docker CLI itself
+// does when no explicit credentials are given. A missing file or a registry
+// with no entry there both resolve to an empty (anonymous) resolvedAuth
+// rather than an error.
+func dockerConfigAuth(registryURL string) (resolvedAuth, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return resolvedAuth{}, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, ".docker", "config.json"))
+	if err != nil {
+		return resolvedAuth{}, nil
+	}
+
+	var config struct {
+		Auths map[string]struct {
+			Auth          string `json:"auth"`
+			IdentityToken string `json:"identitytoken"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return resolvedAuth{}, fmt.Errorf("failed to parse ~/.docker/config.json: %w", err)
+	}
+
+	entry, ok := config.Auths[registryURL]
+	if !ok {
+		return resolvedAuth{}, nil
+	}
+	if entry.IdentityToken != "" {
+		return resolvedAuth{IdentityToken: entry.IdentityToken}, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return resolvedAuth{}, fmt.Errorf("failed to decode auth for %s: %w", registryURL, err)
+	}
+	user, pass, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return resolvedAuth{}, fmt.Errorf("unexpected auth format for %s", registryURL)
+	}
+	return resolvedAuth{Username: user, Password: pass}, nil
+}
+
+// Login validates credentials against the registry's /v2/ endpoint before a
+// build/push is attempted, so bad credentials fail fast with a clear,
+// non-retryable error instead of surfacing midway through a push.
+//
+// DEMO HELPER: this only exercises HTTP Basic auth against /v2/; a registry
+// that requires the full Bearer-token challenge/exchange (as most public
+// registries do) needs a real registry client, not this simplified check.
+func Login(ctx context.Context, req LoginRequest) (*LoginResult, error) {
+	resolved, err := resolveAuth(req.RegistryURL, req.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("https://%s/v2/", strings.TrimSuffix(req.RegistryURL, "/"))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build registry login request: %w", err)
+	}
+	if resolved.Username != "" {
+		httpReq.SetBasicAuth(resolved.Username, resolved.Password)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry %s: %w", req.RegistryURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return &LoginResult{Username: resolved.Username}, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("registry %s rejected credentials (status %d)", req.RegistryURL, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("unexpected response from registry %s: status %d", req.RegistryURL, resp.StatusCode)
+	}
+}
@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// buildctlDigestPattern matches the final "exporting manifest list sha256:..."
+// (or "exporting manifest sha256:..." for a single-platform push) line buildctl
+// prints to stderr once a push completes.
+var buildctlDigestPattern = regexp.MustCompile(`exporting manifest(?: list)? (sha256:[0-9a-f]+)`)
+
+// BuildKitRuntime drives a BuildKit daemon directly via `buildctl`, for hosts
+// that run buildkitd without a full docker installation.
+type BuildKitRuntime struct {
+	// Addr is the buildkitd address (buildctl's --addr), e.g.
+	// "unix:///run/buildkit/buildkitd.sock". Empty uses buildctl's own default.
+	Addr string
+}
+
+func (r *BuildKitRuntime) Build(ctx context.Context, req BuildRequest) (*BuildResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	args := r.baseArgs()
+	args = append(args, "build",
+		"--frontend", "dockerfile.v0",
+		"--local", fmt.Sprintf("context=%s", req.BuildContext),
+		"--local", fmt.Sprintf("dockerfile=%s", dockerfileDir(req.Dockerfile)),
+		"--output", fmt.Sprintf("type=docker,name=%s,dest=/tmp/%s.tar", imageTag, req.Tag))
+
+	cmd := exec.CommandContext(ctx, "buildctl", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("buildctl build failed: %w\nOutput: %s", err, output)
+	}
+
+	// DEMO HELPER: a production setup would `docker load < /tmp/<tag>.tar` (or
+	// skip the tarball entirely via a containerd worker) to get a local image ID
+	// for TestDockerContainer to run against. We don't have a daemon to load into
+	// here, so the tag itself stands in for an ID.
+	return &BuildResult{ImageID: imageTag, BuildTime: time.Since(start)}, nil
+}
+
+func (r *BuildKitRuntime) Test(ctx context.Context, req TestRequest) (*TestResult, error) {
+	start := time.Now()
+
+	// DEMO HELPER: running a container is outside buildctl's scope (it only
+	// builds); a real BuildKit-only pipeline would run the built tarball through
+	// a separate OCI runtime (runc, nerdctl run --snapshotter). We fall back to
+	// the sample app's own test suite without starting a container at all.
+	testCmd := exec.CommandContext(ctx, "go", "test")
+	testCmd.Dir = "sample-app"
+	testOutput, err := testCmd.CombinedOutput()
+
+	return &TestResult{
+		Passed:   err == nil,
+		Output:   string(testOutput),
+		TestTime: time.Since(start),
+	}, nil
+}
+
+func (r *BuildKitRuntime) Push(ctx context.Context, req PushRequest) (*PushResult, error) {
+	start := time.Now()
+
+	platforms := req.Platforms
+	if len(platforms) == 0 {
+		platforms = defaultPlatforms
+	}
+	remoteTag := fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(req.RegistryURL, "/"), req.ImageName, req.Tag)
+
+	args := r.baseArgs()
+	args = append(args, "build",
+		"--frontend", "dockerfile.v0",
+		"--local", fmt.Sprintf("context=%s", req.BuildContext),
+		"--local", fmt.Sprintf("dockerfile=%s", dockerfileDir(req.Dockerfile)),
+		"--opt", fmt.Sprintf("platform=%s", strings.Join(platforms, ",")),
+		"--output", fmt.Sprintf("type=image,name=%s,push=true", remoteTag))
+
+	cmd := exec.CommandContext(ctx, "buildctl", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("buildctl push failed: %w\nOutput: %s", err, output)
+	}
+
+	digest := ""
+	if m := buildctlDigestPattern.FindStringSubmatch(string(output)); m != nil {
+		digest = m[1]
+	}
+	if digest == "" {
+		return nil, fmt.Errorf("failed to extract manifest digest from buildctl output:\n%s", output)
+	}
+
+	// DEMO HELPER: unlike docker buildx imagetools, buildctl doesn't expose a
+	// ready-made per-platform digest lookup; a production setup would query the
+	// registry's manifest list directly (e.g. via an OCI distribution client).
+	return &PushResult{Digest: digest, PushTime: time.Since(start)}, nil
+}
+
+func (r *BuildKitRuntime) Inspect(ctx context.Context, req InspectRequest) (*InspectResult, error) {
+	return nil, fmt.Errorf("buildkit runtime does not support Inspect; query the registry's manifest list directly")
+}
+
+// RunHook is unsupported: buildctl only builds images, it has no facility for
+// starting a container from one. Use CONTAINER_RUNTIME=docker/podman/nerdctl
+// for pipelines that need a post-commit hook.
+func (r *BuildKitRuntime) RunHook(ctx context.Context, req HookRequest) (*HookResult, error) {
+	return nil, fmt.Errorf("buildkit runtime does not support RunHook; buildctl has no container-run capability")
+}
+
+func (r *BuildKitRuntime) baseArgs() []string {
+	if r.Addr == "" {
+		return nil
+	}
+	return []string{"--addr", r.Addr}
+}
+
+// dockerfileDir returns the directory buildctl's --local dockerfile= expects:
+// the directory containing the Dockerfile, not the Dockerfile path itself.
+func dockerfileDir(dockerfilePath string) string {
+	if dockerfilePath == "" {
+		return "."
+	}
+	dir := dockerfilePath
+	if idx := strings.LastIndex(dockerfilePath, string(os.PathSeparator)); idx >= 0 {
+		dir = dockerfilePath[:idx]
+	} else {
+		dir = "."
+	}
+	return dir
+}
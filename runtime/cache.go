@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// manifestAcceptHeaders covers every manifest media type a registry might
+// serve for an image reference, so the HEAD below matches regardless of
+// whether the image is a single-platform manifest or a multi-arch list.
+var manifestAcceptHeaders = strings.Join([]string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}, ", ")
+
+// ManifestCheckRequest identifies a single registry to probe for an existing
+// image, by tag or digest.
+type ManifestCheckRequest struct {
+	RegistryURL string
+	Insecure    bool
+	ImageName   string
+	Ref         string // a tag or a "sha256:..." digest
+	Auth        RegistryAuth
+}
+
+// ManifestCheckResult reports whether the manifest was found and, if so, the
+// digest the registry reports for it.
+type ManifestCheckResult struct {
+	Exists bool
+	Digest string
+}
+
+// CheckManifestExists HEADs the registry's manifest endpoint directly,
+// avoiding a pull just to learn whether the content is already there.
+//
+// DEMO HELPER: treats any non-200 response (404, 401, 403, ...) as "not
+// found" rather than distinguishing auth failures from a genuine miss; a
+// production pull-through cache would want that distinction to avoid masking
+// credential problems.
+func CheckManifestExists(ctx context.Context, req ManifestCheckRequest) (*ManifestCheckResult, error) {
+	resolved, err := resolveAuth(req.RegistryURL, req.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := "https"
+	if req.Insecure {
+		scheme = "http"
+	}
+	url := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, strings.TrimSuffix(req.RegistryURL, "/"), req.ImageName, req.Ref)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build manifest check request: %w", err)
+	}
+	httpReq.Header.Set("Accept", manifestAcceptHeaders)
+	if resolved.Username != "" {
+		httpReq.SetBasicAuth(resolved.Username, resolved.Password)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry %s: %w", req.RegistryURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &ManifestCheckResult{Exists: false}, nil
+	}
+
+	return &ManifestCheckResult{Exists: true, Digest: resp.Header.Get("Docker-Content-Digest")}, nil
+}
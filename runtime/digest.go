@@ -0,0 +1,19 @@
+package runtime
+
+import "strings"
+
+// extractDigestLine extracts a "digest: sha256:..." line from CLI push
+// output, the format docker/podman/nerdctl all share for a successful push.
+// It returns "" if no digest line is found, so callers can tell extraction
+// failed rather than returning a placeholder.
+func extractDigestLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, "digest:") && strings.Contains(line, "sha256:") {
+			parts := strings.Split(line, "sha256:")
+			if len(parts) >= 2 {
+				return "sha256:" + strings.TrimSpace(parts[1])
+			}
+		}
+	}
+	return ""
+}
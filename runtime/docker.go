@@ -0,0 +1,453 @@
+package runtime
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// defaultPlatforms matches this repo's original multi-arch push behavior.
+var defaultPlatforms = []string{"linux/amd64", "linux/arm64"}
+
+// DockerRuntime talks to the local Docker daemon through the Engine API
+// client instead of shelling out to the docker CLI, so build/push progress
+// comes back as structured JSON messages (for heartbeating) and errors are
+// typed instead of scraped from stdout.
+//
+// DEMO HELPER: the classic Engine API builder doesn't support BuildKit-style
+// multi-platform manifest lists, so Push only ever builds/pushes for the
+// host platform here; select CONTAINER_RUNTIME=buildkit for real multi-arch.
+type DockerRuntime struct{}
+
+func newEngineClient() (*client.Client, error) {
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
+
+// encodeRegistryAuth resolves auth (explicit creds, a credential helper, or
+// ~/.docker/config.json) and base64-encodes it as the Engine API's
+// X-Registry-Auth header expects.
+func encodeRegistryAuth(registryURL string, auth RegistryAuth) (string, error) {
+	resolved, err := resolveAuth(registryURL, auth)
+	if err != nil {
+		return "", err
+	}
+	authConfig, err := json.Marshal(types.AuthConfig{
+		Username:      resolved.Username,
+		Password:      resolved.Password,
+		IdentityToken: resolved.IdentityToken,
+		ServerAddress: registryURL,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(authConfig), nil
+}
+
+func (r *DockerRuntime) Build(ctx context.Context, req BuildRequest) (*BuildResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	cli, err := newEngineClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+
+	buildCtx, err := archive.TarWithOptions(req.BuildContext, &archive.TarOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to tar build context: %w", err)
+	}
+	defer buildCtx.Close()
+
+	resp, err := cli.ImageBuild(ctx, buildCtx, types.ImageBuildOptions{
+		Tags:       []string{imageTag},
+		Dockerfile: req.Dockerfile,
+		Remove:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker build failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := streamProgress(resp.Body, req.OnProgress); err != nil {
+		return nil, fmt.Errorf("docker build failed: %w", err)
+	}
+
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, imageTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect built image: %w", err)
+	}
+
+	return &BuildResult{
+		ImageID:   inspect.ID,
+		BuildTime: time.Since(start),
+	}, nil
+}
+
+func (r *DockerRuntime) Test(ctx context.Context, req TestRequest) (*TestResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+	containerName := fmt.Sprintf("test-%s-%d", req.Tag, time.Now().Unix())
+
+	cli, err := newEngineClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: imageTag,
+		ExposedPorts: nat.PortSet{
+			"8080/tcp": struct{}{},
+		},
+	}, &container.HostConfig{
+		PortBindings: nat.PortMap{
+			"8080/tcp": []nat.PortBinding{{HostIP: "127.0.0.1"}},
+		},
+	}, nil, nil, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	defer cli.ContainerRemove(ctx, created.ID, types.ContainerRemoveOptions{Force: true})
+
+	if err := cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	info, err := cli.ContainerInspect(ctx, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	bindings, ok := info.NetworkSettings.Ports["8080/tcp"]
+	if !ok || len(bindings) == 0 {
+		return nil, fmt.Errorf("container did not publish port 8080")
+	}
+	port := bindings[0].HostPort
+
+	if req.Probe.Type == "exec" {
+		err = dockerExecProbe(ctx, cli, created.ID, req.Probe, req.OnProbeAttempt)
+	} else {
+		err = waitUntilReady(ctx, fmt.Sprintf("localhost:%s", port), req.Probe, req.OnProbeAttempt)
+	}
+	if err != nil {
+		cLogs := fetchContainerLogs(ctx, cli, created.ID)
+		return nil, fmt.Errorf("container never became ready: %w\nContainer logs:\n%s", err, cLogs)
+	}
+
+	testCmd := exec.CommandContext(ctx, "go", "test")
+	testCmd.Dir = "sample-app"
+	testCmd.Env = append(os.Environ(), fmt.Sprintf("BASE_URL=http://localhost:%s", port))
+	testOutput, err := testCmd.CombinedOutput()
+
+	return &TestResult{
+		Passed:   err == nil,
+		Output:   string(testOutput),
+		TestTime: time.Since(start),
+	}, nil
+}
+
+// dockerExecProbe runs req.Command inside the already-running containerID and
+// treats a zero exit code as ready, the way a Kubernetes exec probe does.
+func dockerExecProbe(ctx context.Context, cli *client.Client, containerID string, probe ReadinessProbe, onAttempt OnProbeAttempt) error {
+	if len(probe.Command) == 0 {
+		return fmt.Errorf("exec probe requires a Command")
+	}
+	return pollReady(ctx, probe, func(attemptCtx context.Context) (bool, string) {
+		return runDockerExecOnce(attemptCtx, cli, containerID, probe.Command)
+	}, onAttempt)
+}
+
+func runDockerExecOnce(ctx context.Context, cli *client.Client, containerID string, command []string) (ready bool, detail string) {
+	execID, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd: command, AttachStdout: true, AttachStderr: true,
+	})
+	if err != nil {
+		return false, err.Error()
+	}
+	if err := cli.ContainerExecStart(ctx, execID.ID, types.ExecStartCheck{}); err != nil {
+		return false, err.Error()
+	}
+	inspect, err := cli.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return false, err.Error()
+	}
+	if inspect.ExitCode != 0 {
+		return false, fmt.Sprintf("exec probe %v exited %d", command, inspect.ExitCode)
+	}
+	return true, fmt.Sprintf("exec probe %v exited 0", command)
+}
+
+// fetchContainerLogs reads everything containerID has written to
+// stdout/stderr, for attaching to a readiness-probe failure so the cause of a
+// slow or broken startup doesn't have to be re-diagnosed by hand. Best-effort:
+// an error fetching logs is folded into the returned string rather than
+// failing the caller a second time.
+func fetchContainerLogs(ctx context.Context, cli *client.Client, containerID string) string {
+	logs, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return fmt.Sprintf("(failed to fetch container logs: %v)", err)
+	}
+	defer logs.Close()
+
+	lw := &lineWriter{}
+	if _, err := stdcopy.StdCopy(lw, lw, logs); err != nil && err != io.EOF {
+		return fmt.Sprintf("(failed to read container logs: %v)", err)
+	}
+	return lw.output.String()
+}
+
+func (r *DockerRuntime) Push(ctx context.Context, req PushRequest) (*PushResult, error) {
+	start := time.Now()
+	remoteTag := fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(req.RegistryURL, "/"), req.ImageName, req.Tag)
+
+	cli, err := newEngineClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+
+	buildCtx, err := archive.TarWithOptions(req.BuildContext, &archive.TarOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to tar build context: %w", err)
+	}
+	defer buildCtx.Close()
+
+	buildResp, err := cli.ImageBuild(ctx, buildCtx, types.ImageBuildOptions{
+		Tags:       []string{remoteTag},
+		Dockerfile: req.Dockerfile,
+		Remove:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("docker build failed: %w", err)
+	}
+	defer buildResp.Body.Close()
+	if err := streamProgress(buildResp.Body, req.OnProgress); err != nil {
+		return nil, fmt.Errorf("docker build failed: %w", err)
+	}
+
+	encodedAuth, err := encodeRegistryAuth(req.RegistryURL, req.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve registry credentials: %w", err)
+	}
+
+	pushResp, err := cli.ImagePush(ctx, remoteTag, types.ImagePushOptions{RegistryAuth: encodedAuth})
+	if err != nil {
+		return nil, fmt.Errorf("docker push failed: %w", err)
+	}
+	defer pushResp.Close()
+
+	digest, err := streamPushProgress(pushResp, req.OnProgress)
+	if err != nil {
+		return nil, fmt.Errorf("docker push failed: %w", err)
+	}
+	if digest == "" {
+		return nil, fmt.Errorf("push response did not include a digest for %s", remoteTag)
+	}
+
+	return &PushResult{
+		Digest:   digest,
+		PushTime: time.Since(start),
+	}, nil
+}
+
+func (r *DockerRuntime) RunHook(ctx context.Context, req HookRequest) (*HookResult, error) {
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	cli, err := newEngineClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+
+	cmd := req.Command
+	if len(cmd) == 0 {
+		cmd = []string{"/bin/sh", "-c", req.Script}
+	} else {
+		cmd = append(append([]string{}, cmd...), req.Args...)
+	}
+
+	env := make([]string, 0, len(req.Env))
+	for k, v := range req.Env {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	created, err := cli.ContainerCreate(ctx, &container.Config{
+		Image: imageTag,
+		Cmd:   cmd,
+		Env:   env,
+	}, nil, nil, nil, req.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hook container: %w", err)
+	}
+	// Cleanup must outlive ctx: if the hook hangs past the activity's
+	// StartToCloseTimeout, ctx is already canceled by the time we get here, and
+	// removing the container with it would fail immediately, leaking it.
+	defer func() {
+		removeCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		cli.ContainerRemove(removeCtx, created.ID, types.ContainerRemoveOptions{Force: true})
+	}()
+
+	logs, err := cli.ContainerAttach(ctx, created.ID, types.ContainerAttachOptions{
+		Stream: true, Stdout: true, Stderr: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to hook container: %w", err)
+	}
+	defer logs.Close()
+
+	if err := cli.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start hook container: %w", err)
+	}
+
+	lw := &lineWriter{onLine: req.OnOutput}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stdcopy.StdCopy(lw, lw, logs.Reader)
+	}()
+
+	statusCh, errCh := cli.ContainerWait(ctx, created.ID, container.WaitConditionNotRunning)
+	var exitCode int
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return nil, fmt.Errorf("failed waiting for hook container: %w", err)
+		}
+	case status := <-statusCh:
+		exitCode = int(status.StatusCode)
+	}
+	<-done
+
+	return &HookResult{ExitCode: exitCode, Output: lw.output.String()}, nil
+}
+
+// lineWriter accumulates demuxed container output and, if onLine is set,
+// forwards each complete line as it arrives (for heartbeating long hooks).
+type lineWriter struct {
+	output  strings.Builder
+	partial strings.Builder
+	onLine  func(string)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+	for _, b := range p {
+		if b == '\n' {
+			if w.onLine != nil {
+				w.onLine(w.partial.String())
+			}
+			w.partial.Reset()
+			continue
+		}
+		w.partial.WriteByte(b)
+	}
+	return len(p), nil
+}
+
+func (r *DockerRuntime) Inspect(ctx context.Context, req InspectRequest) (*InspectResult, error) {
+	cli, err := newEngineClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer cli.Close()
+
+	authConfig, _ := json.Marshal(types.AuthConfig{})
+	encodedAuth := base64.URLEncoding.EncodeToString(authConfig)
+
+	dist, err := cli.DistributionInspect(ctx, req.ImageRef, encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", req.ImageRef, err)
+	}
+
+	platforms := make([]string, 0, len(dist.Platforms))
+	for _, p := range dist.Platforms {
+		platforms = append(platforms, fmt.Sprintf("%s/%s", p.OS, p.Architecture))
+	}
+
+	return &InspectResult{ImageRef: req.ImageRef, Platforms: platforms}, nil
+}
+
+// streamProgress decodes the JSON message stream ImageBuild/ImagePush return,
+// forwarding each layer's progress to onProgress (if set) and returning the
+// first error message embedded in the stream, if any.
+func streamProgress(body io.Reader, onProgress func(ProgressEvent)) error {
+	decoder := json.NewDecoder(body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if onProgress != nil {
+			event := ProgressEvent{Layer: msg.ID, Status: msg.Status}
+			if msg.Progress != nil {
+				event.Current = msg.Progress.Current
+				event.Total = msg.Progress.Total
+			}
+			onProgress(event)
+		}
+	}
+}
+
+// pushAux mirrors the `aux` payload ImagePush emits on its final message,
+// which carries the pushed manifest's digest directly instead of requiring
+// it to be scraped from human-readable text.
+type pushAux struct {
+	Tag    string `json:"Tag"`
+	Digest string `json:"Digest"`
+	Size   int    `json:"Size"`
+}
+
+// streamPushProgress is streamProgress's push-specific counterpart: alongside
+// forwarding progress, it captures the digest reported in the stream's final
+// aux message.
+func streamPushProgress(body io.Reader, onProgress func(ProgressEvent)) (string, error) {
+	digest := ""
+	decoder := json.NewDecoder(body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return digest, nil
+			}
+			return digest, err
+		}
+		if msg.Error != nil {
+			return digest, msg.Error
+		}
+		if msg.Aux != nil {
+			var aux pushAux
+			if err := json.Unmarshal(*msg.Aux, &aux); err == nil && aux.Digest != "" {
+				digest = aux.Digest
+			}
+		}
+		if onProgress != nil {
+			event := ProgressEvent{Layer: msg.ID, Status: msg.Status}
+			if msg.Progress != nil {
+				event.Current = msg.Progress.Current
+				event.Total = msg.Progress.Total
+			}
+			onProgress(event)
+		}
+	}
+}
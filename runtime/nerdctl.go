@@ -0,0 +1,167 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// NerdctlRuntime shells out to nerdctl, the containerd-native CLI. Its
+// build/run/push syntax (and even its multi-arch `--platform` flag on build,
+// same as buildx) is docker-compatible, so this is nearly identical to
+// DockerRuntime with a different binary name.
+type NerdctlRuntime struct{}
+
+func (r *NerdctlRuntime) Build(ctx context.Context, req BuildRequest) (*BuildResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	args := []string{"build", "-t", imageTag, "-f", req.Dockerfile}
+	if len(req.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(req.Platforms, ","))
+	}
+	args = append(args, req.BuildContext)
+
+	cmd := exec.CommandContext(ctx, "nerdctl", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("nerdctl build failed: %w\nOutput: %s", err, output)
+	}
+
+	idCmd := exec.CommandContext(ctx, "nerdctl", "images", "-q", imageTag)
+	imageIDBytes, err := idCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image ID: %w", err)
+	}
+
+	return &BuildResult{
+		ImageID:   strings.TrimSpace(string(imageIDBytes)),
+		BuildTime: time.Since(start),
+	}, nil
+}
+
+func (r *NerdctlRuntime) Test(ctx context.Context, req TestRequest) (*TestResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+	containerName := fmt.Sprintf("test-%s-%d", req.Tag, time.Now().Unix())
+
+	runCmd := exec.CommandContext(ctx, "nerdctl", "run", "-d", "--name", containerName, "-p", "8080", imageTag)
+	if output, err := runCmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w\nOutput: %s", err, output)
+	}
+	defer exec.Command("nerdctl", "rm", "-f", containerName).Run()
+
+	portCmd := exec.CommandContext(ctx, "nerdctl", "port", containerName, "8080")
+	portOutput, err := portCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container port: %w", err)
+	}
+
+	portStr := strings.TrimSpace(string(portOutput))
+	parts := strings.Split(portStr, ":")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("unexpected port format: %s", portStr)
+	}
+	port := parts[len(parts)-1]
+
+	if req.Probe.Type == "exec" {
+		err = cliExecProbe(ctx, "nerdctl", containerName, req.Probe, req.OnProbeAttempt)
+	} else {
+		err = waitUntilReady(ctx, fmt.Sprintf("localhost:%s", port), req.Probe, req.OnProbeAttempt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("container never became ready: %w\nContainer logs:\n%s", err, fetchCLILogs("nerdctl", containerName))
+	}
+
+	testCmd := exec.CommandContext(ctx, "go", "test")
+	testCmd.Dir = "sample-app"
+	testCmd.Env = append(os.Environ(), fmt.Sprintf("BASE_URL=http://localhost:%s", port))
+	testOutput, err := testCmd.CombinedOutput()
+
+	return &TestResult{
+		Passed:   err == nil,
+		Output:   string(testOutput),
+		TestTime: time.Since(start),
+	}, nil
+}
+
+func (r *NerdctlRuntime) Push(ctx context.Context, req PushRequest) (*PushResult, error) {
+	start := time.Now()
+
+	platforms := req.Platforms
+	if len(platforms) == 0 {
+		platforms = defaultPlatforms
+	}
+	remoteTag := fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(req.RegistryURL, "/"), req.ImageName, req.Tag)
+
+	buildCmd := exec.CommandContext(ctx, "nerdctl", "build",
+		"--platform", strings.Join(platforms, ","),
+		"-t", remoteTag,
+		"-f", req.Dockerfile,
+		req.BuildContext)
+	if output, err := buildCmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("nerdctl multi-arch build failed: %w\nOutput: %s", err, output)
+	}
+
+	pushCmd := exec.CommandContext(ctx, "nerdctl", "push", "--all-platforms", remoteTag)
+	output, err := pushCmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("nerdctl push failed: %w\nOutput: %s", err, output)
+	}
+
+	digest := extractDigestLine(string(output))
+	if digest == "" {
+		return nil, fmt.Errorf("failed to extract manifest digest from nerdctl push output:\n%s", output)
+	}
+
+	// DEMO HELPER: per-platform digests aren't parsed here; see
+	// DockerRuntime.Push/Inspect for the full buildx imagetools version.
+	return &PushResult{Digest: digest, PushTime: time.Since(start)}, nil
+}
+
+// RunHook runs req.Script (or req.Command) in an ephemeral container via the
+// nerdctl CLI, mirroring DockerRuntime.RunHook/PodmanRuntime.RunHook.
+func (r *NerdctlRuntime) RunHook(ctx context.Context, req HookRequest) (*HookResult, error) {
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	args := []string{"run", "--name", req.Name}
+	for k, v := range req.Env {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, imageTag)
+	if len(req.Command) > 0 {
+		args = append(args, req.Command...)
+		args = append(args, req.Args...)
+	} else {
+		args = append(args, "/bin/sh", "-c", req.Script)
+	}
+
+	cmd := exec.CommandContext(ctx, "nerdctl", args...)
+	defer exec.Command("nerdctl", "rm", "-f", req.Name).Run()
+
+	lw := &lineWriter{onLine: req.OnOutput}
+	cmd.Stdout = lw
+	cmd.Stderr = lw
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, fmt.Errorf("failed to run hook container: %w", err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &HookResult{ExitCode: exitCode, Output: lw.output.String()}, nil
+}
+
+func (r *NerdctlRuntime) Inspect(ctx context.Context, req InspectRequest) (*InspectResult, error) {
+	cmd := exec.CommandContext(ctx, "nerdctl", "image", "inspect", req.ImageRef)
+	if _, err := cmd.Output(); err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", req.ImageRef, err)
+	}
+	return &InspectResult{ImageRef: req.ImageRef}, nil
+}
@@ -0,0 +1,177 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// PodmanRuntime shells out to the podman CLI. Podman's build/run/push syntax
+// is close enough to docker's that this mirrors DockerRuntime almost exactly;
+// the one real difference is multi-arch push, which podman does through a
+// manifest list rather than buildx.
+type PodmanRuntime struct{}
+
+func (r *PodmanRuntime) Build(ctx context.Context, req BuildRequest) (*BuildResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	args := []string{"build", "-t", imageTag, "-f", req.Dockerfile}
+	if len(req.Platforms) > 0 {
+		args = append(args, "--platform", strings.Join(req.Platforms, ","))
+	}
+	args = append(args, req.BuildContext)
+
+	cmd := exec.CommandContext(ctx, "podman", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("podman build failed: %w\nOutput: %s", err, output)
+	}
+
+	idCmd := exec.CommandContext(ctx, "podman", "images", "-q", imageTag)
+	imageIDBytes, err := idCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image ID: %w", err)
+	}
+
+	return &BuildResult{
+		ImageID:   strings.TrimSpace(string(imageIDBytes)),
+		BuildTime: time.Since(start),
+	}, nil
+}
+
+func (r *PodmanRuntime) Test(ctx context.Context, req TestRequest) (*TestResult, error) {
+	start := time.Now()
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+	containerName := fmt.Sprintf("test-%s-%d", req.Tag, time.Now().Unix())
+
+	runCmd := exec.CommandContext(ctx, "podman", "run", "-d", "--name", containerName, "-p", "8080", imageTag)
+	if output, err := runCmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w\nOutput: %s", err, output)
+	}
+	defer exec.Command("podman", "rm", "-f", containerName).Run()
+
+	portCmd := exec.CommandContext(ctx, "podman", "port", containerName, "8080")
+	portOutput, err := portCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container port: %w", err)
+	}
+
+	portStr := strings.TrimSpace(string(portOutput))
+	parts := strings.Split(portStr, ":")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("unexpected port format: %s", portStr)
+	}
+	port := parts[len(parts)-1]
+
+	if req.Probe.Type == "exec" {
+		err = cliExecProbe(ctx, "podman", containerName, req.Probe, req.OnProbeAttempt)
+	} else {
+		err = waitUntilReady(ctx, fmt.Sprintf("localhost:%s", port), req.Probe, req.OnProbeAttempt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("container never became ready: %w\nContainer logs:\n%s", err, fetchCLILogs("podman", containerName))
+	}
+
+	testCmd := exec.CommandContext(ctx, "go", "test")
+	testCmd.Dir = "sample-app"
+	testCmd.Env = append(os.Environ(), fmt.Sprintf("BASE_URL=http://localhost:%s", port))
+	testOutput, err := testCmd.CombinedOutput()
+
+	return &TestResult{
+		Passed:   err == nil,
+		Output:   string(testOutput),
+		TestTime: time.Since(start),
+	}, nil
+}
+
+func (r *PodmanRuntime) Push(ctx context.Context, req PushRequest) (*PushResult, error) {
+	start := time.Now()
+
+	platforms := req.Platforms
+	if len(platforms) == 0 {
+		platforms = defaultPlatforms
+	}
+	remoteTag := fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(req.RegistryURL, "/"), req.ImageName, req.Tag)
+	manifestName := fmt.Sprintf("%s-manifest", req.Tag)
+
+	exec.Command("podman", "manifest", "rm", manifestName).Run() // best-effort cleanup from a previous run
+
+	createCmd := exec.CommandContext(ctx, "podman", "build",
+		"--platform", strings.Join(platforms, ","),
+		"--manifest", manifestName,
+		"-f", req.Dockerfile,
+		req.BuildContext)
+	if output, err := createCmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("podman multi-arch build failed: %w\nOutput: %s", err, output)
+	}
+
+	pushCmd := exec.CommandContext(ctx, "podman", "manifest", "push", "--all", manifestName, "docker://"+remoteTag)
+	output, err := pushCmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("podman manifest push failed: %w\nOutput: %s", err, output)
+	}
+
+	digest := extractDigestLine(string(output))
+	if digest == "" {
+		return nil, fmt.Errorf("failed to extract manifest digest from podman push output:\n%s", output)
+	}
+
+	// DEMO HELPER: a production setup would follow up with `podman manifest
+	// inspect` (or a registry client) to report each platform's own digest; we
+	// only surface the manifest-list digest here.
+	return &PushResult{Digest: digest, PushTime: time.Since(start)}, nil
+}
+
+// RunHook runs req.Script (or req.Command) in an ephemeral container, the
+// same way DockerRuntime.RunHook does, but via the podman CLI. Output is
+// streamed line-by-line to req.OnOutput as it's produced and also captured in
+// full for HookResult.Output.
+func (r *PodmanRuntime) RunHook(ctx context.Context, req HookRequest) (*HookResult, error) {
+	imageTag := fmt.Sprintf("%s:%s", req.ImageName, req.Tag)
+
+	args := []string{"run", "--name", req.Name}
+	for k, v := range req.Env {
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, imageTag)
+	if len(req.Command) > 0 {
+		args = append(args, req.Command...)
+		args = append(args, req.Args...)
+	} else {
+		args = append(args, "/bin/sh", "-c", req.Script)
+	}
+
+	cmd := exec.CommandContext(ctx, "podman", args...)
+	defer exec.Command("podman", "rm", "-f", req.Name).Run()
+
+	lw := &lineWriter{onLine: req.OnOutput}
+	cmd.Stdout = lw
+	cmd.Stderr = lw
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, fmt.Errorf("failed to run hook container: %w", err)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	return &HookResult{ExitCode: exitCode, Output: lw.output.String()}, nil
+}
+
+// Inspect confirms the manifest list exists but, unlike DockerRuntime's
+// buildx-backed Inspect, doesn't parse out the per-platform list.
+// DEMO HELPER: a production setup would parse `podman manifest inspect`'s JSON
+// the same way DockerRuntime.Inspect parses buildx imagetools' output.
+func (r *PodmanRuntime) Inspect(ctx context.Context, req InspectRequest) (*InspectResult, error) {
+	cmd := exec.CommandContext(ctx, "podman", "manifest", "inspect", req.ImageRef)
+	if _, err := cmd.Output(); err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", req.ImageRef, err)
+	}
+	return &InspectResult{ImageRef: req.ImageRef}, nil
+}
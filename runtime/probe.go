@@ -0,0 +1,164 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// ReadinessProbe polls a just-started test container until it's actually
+// serving, the same way a Kubernetes readiness probe gates traffic on more
+// than "the process started" — instead of a fixed sleep that either wastes
+// time or races a slow cold start (e.g. under arm64 emulation).
+type ReadinessProbe struct {
+	Type             string        // "http", "tcp", or "exec"; defaults to "tcp"
+	Path             string        // HTTP path to probe; defaults to "/"
+	ExpectedStatus   int           // HTTP status that counts as ready; defaults to 200
+	Command          []string      // exec probe command, run inside the test container
+	Interval         time.Duration // time between attempts; defaults to 500ms
+	Timeout          time.Duration // per-attempt timeout; defaults to 2s
+	FailureThreshold int           // consecutive failures before giving up; defaults to 10
+}
+
+// OnProbeAttempt, when set, is called after every attempt so the caller can
+// heartbeat the activity between them.
+type OnProbeAttempt func(attempt int, ready bool, detail string)
+
+func (p ReadinessProbe) withDefaults() ReadinessProbe {
+	if p.Type == "" {
+		p.Type = "tcp"
+	}
+	if p.Path == "" {
+		p.Path = "/"
+	}
+	if p.ExpectedStatus == 0 {
+		p.ExpectedStatus = http.StatusOK
+	}
+	if p.Interval == 0 {
+		p.Interval = 500 * time.Millisecond
+	}
+	if p.Timeout == 0 {
+		p.Timeout = 2 * time.Second
+	}
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = 10
+	}
+	return p
+}
+
+// pollReady retries check up to probe.FailureThreshold times, probe.Interval
+// apart, each attempt bounded by probe.Timeout, reporting every attempt to
+// onAttempt. Shared by every runtime's http/tcp/exec probe so the
+// retry/backoff/cancellation behavior only lives in one place.
+func pollReady(ctx context.Context, probe ReadinessProbe, check func(context.Context) (ready bool, detail string), onAttempt OnProbeAttempt) error {
+	probe = probe.withDefaults()
+
+	var lastErr error
+	for attempt := 1; attempt <= probe.FailureThreshold; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, probe.Timeout)
+		ready, detail := check(attemptCtx)
+		cancel()
+
+		if onAttempt != nil {
+			onAttempt(attempt, ready, detail)
+		}
+		if ready {
+			return nil
+		}
+		lastErr = fmt.Errorf("%s", detail)
+
+		if attempt == probe.FailureThreshold {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("readiness probe canceled: %w", ctx.Err())
+		case <-time.After(probe.Interval):
+		}
+	}
+	return fmt.Errorf("container did not become ready after %d attempts: %w", probe.FailureThreshold, lastErr)
+}
+
+// waitUntilReady polls addr (host:port) until it accepts a TCP connection
+// (Type "tcp") or responds with ExpectedStatus on Path (Type "http"). Type
+// "exec" isn't handled here: "run a command inside the container" differs
+// between the Engine API and the podman/nerdctl CLIs, so each runtime calls
+// its own exec probe (dockerExecProbe, cliExecProbe) instead.
+func waitUntilReady(ctx context.Context, addr string, probe ReadinessProbe, onAttempt OnProbeAttempt) error {
+	switch probe.Type {
+	case "", "tcp", "http":
+	default:
+		return fmt.Errorf("unrecognized readiness probe type %q (want tcp, http, or exec)", probe.Type)
+	}
+	return pollReady(ctx, probe, func(attemptCtx context.Context) (bool, string) {
+		return checkOnce(attemptCtx, addr, probe)
+	}, onAttempt)
+}
+
+// cliExecProbe runs probe.Command inside the already-running containerName
+// via `<bin> exec`, treating a zero exit code as ready. Shared by
+// PodmanRuntime and NerdctlRuntime, whose exec/logs syntax is identical.
+func cliExecProbe(ctx context.Context, bin, containerName string, probe ReadinessProbe, onAttempt OnProbeAttempt) error {
+	if len(probe.Command) == 0 {
+		return fmt.Errorf("exec probe requires a Command")
+	}
+	return pollReady(ctx, probe, func(attemptCtx context.Context) (bool, string) {
+		args := append([]string{"exec", containerName}, probe.Command...)
+		output, err := exec.CommandContext(attemptCtx, bin, args...).CombinedOutput()
+		if err != nil {
+			return false, fmt.Sprintf("exec probe %v failed: %v\n%s", probe.Command, err, truncate(output, 2000))
+		}
+		return true, fmt.Sprintf("exec probe %v exited 0", probe.Command)
+	}, onAttempt)
+}
+
+// fetchCLILogs is the podman/nerdctl counterpart to DockerRuntime's
+// fetchContainerLogs, run via `<bin> logs` instead of the Engine API.
+func fetchCLILogs(bin, containerName string) string {
+	output, err := exec.Command(bin, "logs", containerName).CombinedOutput()
+	if err != nil {
+		return fmt.Sprintf("(failed to fetch container logs: %v)\n%s", err, output)
+	}
+	return string(output)
+}
+
+func checkOnce(ctx context.Context, addr string, probe ReadinessProbe) (ready bool, detail string) {
+	probe = probe.withDefaults()
+	switch probe.Type {
+	case "http":
+		url := fmt.Sprintf("http://%s%s", addr, probe.Path)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, err.Error()
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false, err.Error()
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != probe.ExpectedStatus {
+			return false, fmt.Sprintf("GET %s returned status %d, want %d", url, resp.StatusCode, probe.ExpectedStatus)
+		}
+		return true, fmt.Sprintf("GET %s returned status %d", url, resp.StatusCode)
+	default: // "tcp"
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return false, err.Error()
+		}
+		conn.Close()
+		return true, fmt.Sprintf("tcp connect to %s succeeded", addr)
+	}
+}
+
+// truncate caps a probe's captured output at n bytes so a chatty health-check
+// command can't bloat every heartbeat/log line it's embedded in.
+func truncate(b []byte, n int) []byte {
+	if len(b) <= n {
+		return b
+	}
+	return append(b[:n:n], []byte(fmt.Sprintf("... (truncated, %d bytes total)", len(b)))...)
+}
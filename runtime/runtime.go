@@ -0,0 +1,149 @@
+// Package runtime abstracts the container build/test/push/inspect operations
+// the Docker activities need behind a ContainerRuntime interface, so the
+// worker can target docker, BuildKit, Podman, or containerd/nerdctl instead of
+// assuming the docker CLI is what's installed on the build host.
+package runtime
+
+import (
+	"context"
+	"time"
+)
+
+// ProgressEvent reports a single line of build/push progress (one per image
+// layer), for runtimes that can stream structured progress instead of plain
+// text output.
+type ProgressEvent struct {
+	Layer   string
+	Status  string
+	Current int64
+	Total   int64
+}
+
+// BuildRequest builds a local image for testing. Platforms, when non-empty,
+// builds a multi-arch image (e.g. ["linux/amd64", "linux/arm64"]); when empty
+// the image is built for the host platform only. OnProgress, when set, is
+// called for every layer progress update a runtime can report; runtimes that
+// can't stream structured progress simply leave it uncalled.
+type BuildRequest struct {
+	ImageName    string
+	Tag          string
+	Dockerfile   string
+	BuildContext string
+	Platforms    []string
+	OnProgress   func(ProgressEvent)
+}
+
+type BuildResult struct {
+	ImageID   string
+	BuildTime time.Duration
+}
+
+// TestRequest runs the sample app's test suite against a container started
+// from the built image. Probe gates the test run on the container actually
+// being ready to serve instead of a fixed sleep; its zero value falls back to
+// a single TCP-connect check against the published port.
+type TestRequest struct {
+	ImageName      string
+	Tag            string
+	Probe          ReadinessProbe
+	OnProbeAttempt OnProbeAttempt
+}
+
+type TestResult struct {
+	Passed   bool
+	Output   string
+	TestTime time.Duration
+}
+
+// PushRequest builds (if needed) and pushes an image to RegistryURL. Platforms
+// defaults to ["linux/amd64", "linux/arm64"] when empty, matching this repo's
+// original multi-arch push behavior.
+type PushRequest struct {
+	ImageName    string
+	Tag          string
+	RegistryURL  string
+	Dockerfile   string
+	BuildContext string
+	Platforms    []string
+	Auth         RegistryAuth
+	OnProgress   func(ProgressEvent)
+}
+
+// RegistryAuth mirrors shared.RegistryAuth; it's redeclared here so this
+// package's request types don't depend on the activities-facing shared
+// package. Exactly one resolution strategy is normally populated: static
+// Username/Password (or IdentityToken), or CredentialHelper to shell out to
+// docker-credential-<name>. All fields empty falls back to whatever's
+// already in ~/.docker/config.json.
+type RegistryAuth struct {
+	Username         string
+	Password         string
+	IdentityToken    string
+	CredentialHelper string
+}
+
+// LoginRequest validates a set of credentials against a registry's /v2/
+// endpoint without performing a build or push.
+type LoginRequest struct {
+	RegistryURL string
+	Auth        RegistryAuth
+}
+
+// LoginResult reports the identity the registry accepted.
+type LoginResult struct {
+	Username string
+}
+
+// PushResult reports the digest of what was actually pushed: Digest is the
+// manifest-list digest callers should deploy by (content-addressable across
+// every platform), and ArchDigests (when the runtime can report it) maps each
+// platform to its own per-architecture image digest.
+type PushResult struct {
+	Digest      string
+	ArchDigests map[string]string
+	PushTime    time.Duration
+}
+
+type InspectRequest struct {
+	ImageRef string
+}
+
+// InspectResult reports what a pushed or built image actually contains,
+// mirroring `docker buildx imagetools inspect`.
+type InspectResult struct {
+	ImageRef  string
+	Platforms []string
+}
+
+// HookRequest runs an arbitrary script or command in an ephemeral container
+// started from an already-built image, for project-specific smoke tests that
+// don't belong in TestDockerContainer's fixed sample-app test. Exactly one of
+// Script or Command is normally set: Script runs via `/bin/sh -c`, Command
+// (with Args) runs directly.
+type HookRequest struct {
+	ImageName string
+	Tag       string
+	Name      string // container name, e.g. postcommit-<workflowID>-<rand>
+	Script    string
+	Command   []string
+	Args      []string
+	Env       map[string]string
+	OnOutput  func(line string)
+}
+
+// HookResult reports how the hook container exited and everything it wrote
+// to stdout/stderr.
+type HookResult struct {
+	ExitCode int
+	Output   string
+}
+
+// ContainerRuntime is the pluggable build/test/push/inspect backend the Docker
+// activities run against.
+type ContainerRuntime interface {
+	Build(ctx context.Context, req BuildRequest) (*BuildResult, error)
+	Test(ctx context.Context, req TestRequest) (*TestResult, error)
+	Push(ctx context.Context, req PushRequest) (*PushResult, error)
+	Inspect(ctx context.Context, req InspectRequest) (*InspectResult, error)
+	RunHook(ctx context.Context, req HookRequest) (*HookResult, error)
+}
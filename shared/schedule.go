@@ -0,0 +1,212 @@
+package shared
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TimeOfDay is a wall-clock time within a day, independent of timezone (the
+// timezone lives on the enclosing DeploymentSchedule).
+type TimeOfDay struct {
+	Hour   int `yaml:"hour"`
+	Minute int `yaml:"minute"`
+}
+
+// DailyWindow allows deploys on a given weekday between Start and End. Start
+// may be after End to express an overnight window (e.g. 22:00 -> 02:00).
+type DailyWindow struct {
+	Weekday time.Weekday `yaml:"weekday"`
+	Start   TimeOfDay    `yaml:"start"`
+	End     TimeOfDay    `yaml:"end"`
+}
+
+// BlackoutDate is a single calendar date (in the schedule's timezone) during
+// which deploys are never allowed, e.g. a holiday or a change freeze.
+type BlackoutDate struct {
+	Date   string `yaml:"date"` // YYYY-MM-DD
+	Reason string `yaml:"reason"`
+}
+
+// DeploymentSchedule replaces the old start/end-hour toy window check with IANA
+// timezone awareness, per-weekday windows, and blackout dates. It is loaded from
+// YAML and threaded through PipelineRequest so the workflow can consult it
+// deterministically via workflow.Now(ctx).
+type DeploymentSchedule struct {
+	Timezone  string         `yaml:"timezone"` // e.g. "America/Los_Angeles"
+	Windows   []DailyWindow  `yaml:"windows"`
+	Blackouts []BlackoutDate `yaml:"blackouts"`
+}
+
+// LoadDeploymentScheduleYAML parses a DeploymentSchedule from YAML, as produced
+// by e.g. a deploy-schedule.yaml checked into the repo.
+func LoadDeploymentScheduleYAML(data []byte) (*DeploymentSchedule, error) {
+	var schedule DeploymentSchedule
+	if err := yaml.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment schedule: %w", err)
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if _, err := schedule.location(); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (s *DeploymentSchedule) location() (*time.Location, error) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+	return loc, nil
+}
+
+// IsWithinWindow reports whether t (any timezone) falls within an allowed
+// deployment window and is not on a blackout date, evaluated in the schedule's
+// own timezone.
+func (s *DeploymentSchedule) IsWithinWindow(t time.Time) (bool, error) {
+	loc, err := s.location()
+	if err != nil {
+		return false, err
+	}
+	local := t.In(loc)
+
+	if s.isBlackedOut(local) {
+		return false, nil
+	}
+
+	if len(s.Windows) == 0 {
+		// No windows configured means deploys are always allowed.
+		return true, nil
+	}
+
+	for _, w := range s.Windows {
+		overnight := isOvernightWindow(w)
+
+		if w.Weekday == local.Weekday() {
+			if overnight {
+				// Only the evening half belongs to today; the early-morning
+				// half before Start is the trailing edge of *yesterday's*
+				// window, handled by the branch below on that prior day's
+				// own iteration.
+				if afterOrAtTimeOfDay(local, w.Start) {
+					return true, nil
+				}
+			} else if withinTimeOfDay(local, w.Start, w.End) {
+				return true, nil
+			}
+			continue
+		}
+		// An overnight window (Start > End) runs past midnight into the
+		// following weekday, e.g. {Friday, 22:00, 02:00} covers Saturday
+		// 00:00-02:00 too. Match that trailing portion here, since the
+		// Weekday equality check above only ever sees it as Saturday. A
+		// blackout on the night the window started (Friday, here) still
+		// applies even though today's own date isn't blacked out.
+		if overnight && local.Weekday() == nextWeekday(w.Weekday) && beforeTimeOfDay(local, w.End) {
+			if s.isBlackedOut(local.AddDate(0, 0, -1)) {
+				continue
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isBlackedOut reports whether local's calendar date is in s.Blackouts.
+func (s *DeploymentSchedule) isBlackedOut(local time.Time) bool {
+	date := local.Format("2006-01-02")
+	for _, b := range s.Blackouts {
+		if date == b.Date {
+			return true
+		}
+	}
+	return false
+}
+
+// withinTimeOfDay reports whether t's time-of-day falls in the same-day
+// range [start, end). Only meaningful for non-overnight windows; overnight
+// windows are handled separately since their two halves fall on different
+// calendar days.
+func withinTimeOfDay(t time.Time, start, end TimeOfDay) bool {
+	minuteOfDay := t.Hour()*60 + t.Minute()
+	return minuteOfDay >= start.Hour*60+start.Minute && minuteOfDay < end.Hour*60+end.Minute
+}
+
+// afterOrAtTimeOfDay reports whether t's time-of-day is at or after start.
+func afterOrAtTimeOfDay(t time.Time, start TimeOfDay) bool {
+	minuteOfDay := t.Hour()*60 + t.Minute()
+	return minuteOfDay >= start.Hour*60+start.Minute
+}
+
+// isOvernightWindow reports whether w's window crosses midnight, i.e. it
+// ends earlier in the day than it starts.
+func isOvernightWindow(w DailyWindow) bool {
+	startMinutes := w.Start.Hour*60 + w.Start.Minute
+	endMinutes := w.End.Hour*60 + w.End.Minute
+	return startMinutes > endMinutes
+}
+
+// nextWeekday returns the weekday immediately after d, wrapping from
+// Saturday to Sunday.
+func nextWeekday(d time.Weekday) time.Weekday {
+	return time.Weekday((int(d) + 1) % 7)
+}
+
+// beforeTimeOfDay reports whether t's time-of-day is earlier than end.
+func beforeTimeOfDay(t time.Time, end TimeOfDay) bool {
+	minuteOfDay := t.Hour()*60 + t.Minute()
+	return minuteOfDay < end.Hour*60+end.Minute
+}
+
+// NextWindowStart returns the next time at or after `after` that IsWithinWindow
+// would return true, searching up to 14 days ahead. It is safe to call from
+// workflow code: it does no I/O and its only input is the time it is given.
+func (s *DeploymentSchedule) NextWindowStart(after time.Time) (time.Time, error) {
+	loc, err := s.location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	local := after.In(loc)
+
+	if len(s.Windows) == 0 {
+		if ok, _ := s.IsWithinWindow(local); ok {
+			return local, nil
+		}
+	}
+
+	// Scan minute-aligned candidates for up to two weeks; deployment windows are
+	// coarse (hour-of-day granularity) so this is cheap and keeps the search simple
+	// and easy to reason about deterministically during workflow replay.
+	candidate := local.Truncate(time.Minute)
+	for i := 0; i < 14*24*60; i++ {
+		ok, err := s.IsWithinWindow(candidate)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok {
+			return candidate, nil
+		}
+		candidate = candidate.Add(time.Minute)
+	}
+	return time.Time{}, fmt.Errorf("no deployment window found within the next 14 days")
+}
+
+// WaitDuration returns how long to sleep from now until the next allowed
+// deployment window. Replaces the old GetDeploymentWindowWaitTime "seconds for
+// demo" hack: this returns a real duration computed against whatever time.Time
+// the caller passes in (the workflow should pass workflow.Now(ctx) so replays
+// stay deterministic).
+func (s *DeploymentSchedule) WaitDuration(now time.Time) (time.Duration, error) {
+	next, err := s.NextWindowStart(now)
+	if err != nil {
+		return 0, err
+	}
+	wait := next.Sub(now)
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
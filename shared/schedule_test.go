@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+// TestIsWithinWindow_OvernightWraparound guards against the Weekday equality
+// check short-circuiting before the overnight wraparound logic runs: a
+// window like {Friday, 22:00, 02:00} must keep matching after midnight, once
+// the calendar day has rolled over to Saturday.
+func TestIsWithinWindow_OvernightWraparound(t *testing.T) {
+	schedule := &DeploymentSchedule{
+		Timezone: "UTC",
+		Windows: []DailyWindow{
+			{Weekday: time.Friday, Start: TimeOfDay{Hour: 22}, End: TimeOfDay{Hour: 2}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		when time.Time
+		want bool
+	}{
+		{"Friday night before start", time.Date(2024, 1, 5, 21, 0, 0, 0, time.UTC), false},
+		{"Friday night within window", time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC), true},
+		{"Friday just after midnight, before start", time.Date(2024, 1, 5, 1, 0, 0, 0, time.UTC), false},
+		{"Saturday just after midnight", time.Date(2024, 1, 6, 1, 0, 0, 0, time.UTC), true},
+		{"Saturday after window ends", time.Date(2024, 1, 6, 3, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schedule.IsWithinWindow(tt.when)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsWithinWindow(%s) = %v, want %v", tt.when, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsWithinWindow_BlackoutCoversOvernightTail verifies that a blackout on
+// the night an overnight window starts also blocks that window's trailing
+// portion after midnight, even though the trailing instant's own calendar
+// date isn't the blacked-out one.
+func TestIsWithinWindow_BlackoutCoversOvernightTail(t *testing.T) {
+	schedule := &DeploymentSchedule{
+		Timezone: "UTC",
+		Windows: []DailyWindow{
+			{Weekday: time.Friday, Start: TimeOfDay{Hour: 22}, End: TimeOfDay{Hour: 2}},
+		},
+		Blackouts: []BlackoutDate{
+			{Date: "2024-01-05", Reason: "change freeze"},
+		},
+	}
+
+	got, err := schedule.IsWithinWindow(time.Date(2024, 1, 6, 1, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got {
+		t.Errorf("IsWithinWindow during the overnight tail of a blacked-out night = true, want false")
+	}
+}
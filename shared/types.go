@@ -1,15 +1,222 @@
 package shared
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Pipeline request types
 type PipelineRequest struct {
-	ImageName    string
-	Tag          string
-	RegistryURL  string
-	Environment  string // staging or production
-	BuildContext string
-	Dockerfile   string
+	ImageName            string
+	Tag                  string
+	RegistryURL          string
+	Environment          string // staging or production
+	BuildContext         string
+	Dockerfile           string
+	Canary               CanaryConfig          // optional; if Steps is empty, production deploys skip the canary rollout
+	Schedule             *DeploymentSchedule   // optional; if nil, production deploys are allowed at any time
+	Approval             ApprovalChannelConfig // selects the human-approval backend for production deploys
+	Clusters             []ClusterTarget       // optional; if non-empty, production deploys fan out via MultiClusterDeployWorkflow
+	ClusterFailureBudget int                   // per-wave failure tolerance when Clusters is set
+	Attestation          AttestationConfig     // optional; if Enabled is false, production deploys skip SBOM/provenance/signing
+	RegistryAuth         RegistryAuth          // optional; falls back to ~/.docker/config.json when empty
+	RegistryMirrors      []string              // optional pull-through cache mirrors, checked before RegistryURL
+	InsecureRegistry     bool                  // optional; talk to RegistryURL/RegistryMirrors over plain HTTP
+	PostCommitHook       PostCommitHookRequest // optional; if Script and Command are both empty, the hook is skipped
+	PrePushScan          PrePushScanConfig     // optional; if Enabled is false, the built image is pushed unscanned
+}
+
+// PrePushScanConfig gates PushToRegistry on a vulnerability scan of the
+// locally built image, before it ever reaches the registry. This is a
+// separate, earlier check from AttestationConfig.Policy, which evaluates the
+// pushed-and-digest-addressed image's SBOM before promoting it to
+// production; PrePushScanConfig catches a vulnerable image before it's
+// published at all.
+type PrePushScanConfig struct {
+	Enabled     bool
+	MaxSeverity string // highest vulnerability severity let through: low, medium, high, or critical
+}
+
+// ClusterTarget describes one destination cluster for a multi-region rollout.
+type ClusterTarget struct {
+	Name        string // human-readable identifier, e.g. "us-east-1"
+	Region      string
+	KubeContext string // kubeconfig context name resolved by KubeContextResolver
+	Weight      int    // relative traffic weight within its wave
+	Wave        int    // deploy order; clusters share a wave deploy in parallel, waves run in order
+}
+
+type MultiClusterDeployRequest struct {
+	ImageTag      string
+	Environment   string
+	Clusters      []ClusterTarget
+	FailureBudget int // number of per-wave cluster failures tolerated before aborting
+}
+
+type MultiClusterDeployResponse struct {
+	Results   map[string]DeployToKubernetesResponse
+	Succeeded []string
+	Failed    []string
+}
+
+type KubeContextResolverRequest struct {
+	ClusterName string
+}
+
+type KubeContextResolverResponse struct {
+	KubeContext string
+	KubeConfig  string // path or raw kubeconfig resolved from the secret store
+}
+
+// AttestationConfig enables SBOM generation, SLSA provenance, and cosign
+// signing for the pushed image, and the policy CICDPipelineWorkflow enforces
+// before promoting it to production. Leaving Enabled false skips the whole
+// phase, the same optional-by-zero-value convention as CanaryConfig.
+type AttestationConfig struct {
+	Enabled   bool
+	BuilderID string     // in-toto builder identity, e.g. the CI system's OIDC subject
+	GitCommit string     // commit the image was built from, recorded in the provenance statement
+	Policy    SBOMPolicy // evaluated by VerifyImagePolicy before production promotion
+}
+
+// SBOMPolicy gates production promotion on the scanned image's SBOM and
+// vulnerability findings.
+type SBOMPolicy struct {
+	MaxSeverity     string   // highest vulnerability severity let through: low, medium, high, or critical
+	AllowedLicenses []string // license allowlist; empty means no license enforcement
+}
+
+// SBOM is a minimal view over a CycloneDX/SPDX document: the packages and
+// licenses AttestationActivities needs for policy evaluation, plus the raw
+// document so it can be attached to the image unchanged.
+type SBOM struct {
+	Format   string // "cyclonedx" or "spdx"
+	ImageRef string
+	Packages []SBOMPackage
+	RawJSON  string
+}
+
+type SBOMPackage struct {
+	Name    string
+	Version string
+	License string
+}
+
+// VulnerabilityFinding is a single scanner match against a package in the SBOM.
+type VulnerabilityFinding struct {
+	ID       string // e.g. CVE-2024-12345
+	Package  string
+	Severity string // low, medium, high, or critical
+}
+
+// ProvenanceStatement is an in-toto SLSA v1.0 provenance statement recording
+// how and from what an image was built.
+type ProvenanceStatement struct {
+	Type          string // in-toto Statement type, "https://in-toto.io/Statement/v1"
+	PredicateType string // "https://slsa.dev/provenance/v1"
+	Subject       ProvenanceSubject
+	BuilderID     string
+	WorkflowID    string
+	RunID         string
+	GitCommit     string
+	Materials     []string // source inputs the build consumed, e.g. the build context
+	BuiltAt       time.Time
+}
+
+// ProvenanceSubject identifies the content-addressable artifact a
+// ProvenanceStatement is about.
+type ProvenanceSubject struct {
+	Name   string // the image reference without the digest, e.g. "registry/image"
+	Digest string // sha256:...
+}
+
+type GenerateSBOMRequest struct {
+	ImageRef string
+	Format   string // cyclonedx or spdx; defaults to cyclonedx
+}
+
+type GenerateSBOMResponse struct {
+	SBOM SBOM
+}
+
+type GenerateProvenanceRequest struct {
+	ImageRef   string
+	WorkflowID string
+	RunID      string
+	GitCommit  string
+	BuilderID  string
+	Materials  []string
+}
+
+type GenerateProvenanceResponse struct {
+	Statement ProvenanceStatement
+}
+
+type SignAndAttachRequest struct {
+	ImageRef   string
+	SBOM       SBOM
+	Provenance ProvenanceStatement
+}
+
+type SignAndAttachResponse struct {
+	Signature string
+}
+
+type VerifyImagePolicyRequest struct {
+	ImageRef string
+	Policy   SBOMPolicy
+}
+
+type VerifyImagePolicyResponse struct {
+	SignatureVerified bool
+	Passed            bool
+	Violations        []string
+}
+
+type ScanImageRequest struct {
+	ImageRef    string
+	MaxSeverity string // optional; if set, ScanImageResponse.Passed reflects this threshold
+}
+
+// VulnerabilityReport summarizes a scanner's findings against an image by
+// severity, alongside the individual findings VerifyImagePolicy also uses.
+type VulnerabilityReport struct {
+	ImageRef string
+	Findings []VulnerabilityFinding
+	Counts   VulnerabilitySeverityCounts
+}
+
+type VulnerabilitySeverityCounts struct {
+	Low      int
+	Medium   int
+	High     int
+	Critical int
+}
+
+type ScanImageResponse struct {
+	Report VulnerabilityReport
+	Passed bool // false when the report exceeds the request's configured MaxSeverity threshold
+}
+
+// PolicyViolation is returned by CICDPipelineWorkflow when VerifyImagePolicy
+// finds an unverifiable signature or an SBOM that fails the configured
+// SBOMPolicy, so callers can distinguish a policy rejection from a generic
+// pipeline failure.
+type PolicyViolation struct {
+	ImageRef   string
+	Violations []string
+}
+
+func (e *PolicyViolation) Error() string {
+	return fmt.Sprintf("policy violation for %s: %s", e.ImageRef, strings.Join(e.Violations, "; "))
+}
+
+// OverrideWindowSignal lets an approver bypass the deployment schedule and
+// proceed immediately, capturing who authorized the override and why.
+type OverrideWindowSignal struct {
+	Approver string
+	Reason   string
 }
 
 // Docker activity types
@@ -18,6 +225,7 @@ type DockerBuildRequest struct {
 	Tag          string
 	BuildContext string
 	Dockerfile   string
+	Platforms    []string // optional; empty builds for the host platform only
 }
 
 type DockerBuildResponse struct {
@@ -28,12 +236,28 @@ type DockerBuildResponse struct {
 type DockerTestRequest struct {
 	ImageName string
 	Tag       string
+	Probe     ReadinessProbe // optional; zero value falls back to a single TCP-connect check
+}
+
+// ReadinessProbe mirrors Kubernetes probe semantics: poll the test container
+// every Interval, up to FailureThreshold consecutive failures, each attempt
+// bounded by Timeout, before giving up. This replaces a fixed sleep between
+// starting the container and running tests against it, which either wastes
+// time or races a slow cold start (e.g. under arm64 emulation).
+type ReadinessProbe struct {
+	Type             string   // "http", "tcp", or "exec"; defaults to "tcp"
+	Path             string   // HTTP path to probe; defaults to "/"
+	ExpectedStatus   int      // HTTP status that counts as ready; defaults to 200
+	Command          []string // exec probe command, run inside the test container
+	Interval         time.Duration
+	Timeout          time.Duration
+	FailureThreshold int
 }
 
 type DockerTestResponse struct {
-	Passed    bool
-	TestTime  time.Duration
-	Output    string
+	Passed   bool
+	TestTime time.Duration
+	Output   string
 }
 
 type DockerPushRequest struct {
@@ -42,11 +266,81 @@ type DockerPushRequest struct {
 	RegistryURL  string
 	BuildContext string
 	Dockerfile   string
+	Platforms    []string     // optional; defaults to linux/amd64,linux/arm64
+	Auth         RegistryAuth // optional; falls back to ~/.docker/config.json when empty
 }
 
 type DockerPushResponse struct {
-	Digest   string
-	PushTime time.Duration
+	Digest      string
+	ArchDigests map[string]string // platform (e.g. "linux/arm64") -> that platform's own image digest, when the runtime can report it
+	PushTime    time.Duration
+}
+
+// PostCommitHookRequest runs a project-defined smoke test inside an ephemeral
+// container started from the just-built image, the same way OpenShift's
+// post-commit build hook runs against a build's output image. Exactly one of
+// Script or Command is normally set: Script runs via `/bin/sh -c`, Command
+// (with Args) runs directly.
+type PostCommitHookRequest struct {
+	ImageName        string
+	Tag              string
+	Script           string
+	Command          []string
+	Args             []string
+	Env              map[string]string
+	FailBuildOnError bool // if true and the hook exits non-zero, RunPostCommitHook returns a non-retryable error
+}
+
+type PostCommitHookResponse struct {
+	ExitCode int
+	Output   string
+	Passed   bool
+}
+
+// RegistryAuth carries credentials for a registry push/login. Exactly one of
+// these resolution strategies is normally populated: Username/Password (or
+// IdentityToken) for static credentials, or CredentialHelper (e.g.
+// "ecr-login", "gcr") to shell out to docker-credential-<name>. Leaving all
+// fields empty falls back to whatever's already in ~/.docker/config.json.
+type RegistryAuth struct {
+	Username         string
+	Password         string
+	IdentityToken    string
+	CredentialHelper string
+}
+
+type RegistryLoginRequest struct {
+	RegistryURL string
+	Auth        RegistryAuth
+}
+
+type RegistryLoginResponse struct {
+	Username string // the identity the registry accepted, for logging
+}
+
+// RegistryConfig describes the primary registry a push targets, an ordered
+// list of pull-through cache mirrors to check first, and whether any of them
+// should be talked to over plain HTTP (local/dev registries).
+type RegistryConfig struct {
+	RegistryURL string
+	Mirrors     []string // checked in order before RegistryURL itself
+	Insecure    bool
+}
+
+// PullThroughCacheRequest asks whether an image is already present in
+// Registry (or one of its Mirrors), so a rebuild can be skipped entirely.
+type PullThroughCacheRequest struct {
+	ImageName string
+	Tag       string
+	Digest    string // optional; check by digest instead of Tag when already known
+	Registry  RegistryConfig
+	Auth      RegistryAuth
+}
+
+type PullThroughCacheResponse struct {
+	Found       bool
+	RegistryURL string // whichever of Registry.Mirrors or Registry.RegistryURL had it
+	Digest      string
 }
 
 // Kubernetes activity types
@@ -66,21 +360,21 @@ type KubernetesDeployResponse struct {
 
 // Approval types
 type ApprovalRequest struct {
-	WorkflowID   string
-	RunID        string
-	Environment  string
-	ImageName    string
-	Tag          string
-	StagingURL   string
-	RequestedBy  string
-	RequestedAt  time.Time
+	WorkflowID  string
+	RunID       string
+	Environment string
+	ImageName   string
+	Tag         string
+	StagingURL  string
+	RequestedBy string
+	RequestedAt time.Time
 }
 
 type ApprovalResponse struct {
-	Approved    bool
-	ApprovedBy  string
-	ApprovedAt  time.Time
-	Comments    string
+	Approved   bool
+	ApprovedBy string
+	ApprovedAt time.Time
+	Comments   string
 }
 
 // Monitoring types
@@ -90,16 +384,18 @@ type HealthCheckRequest struct {
 }
 
 type HealthCheckResponse struct {
-	Healthy       bool
-	ResponseTime  time.Duration
-	StatusCode    int
-	Error         string
+	Healthy      bool
+	ResponseTime time.Duration
+	StatusCode   int
+	Error        string
 }
 
 type RollbackRequest struct {
 	DeploymentName string
 	Namespace      string
-	PreviousTag    string
+	KubeContext    string // optional; selects a non-default cluster via KubernetesActivities.ClientResolver
+	PreviousTag    string // optional: roll back by setting this image directly, skipping revision history lookup
+	ToRevision     int64  // optional: roll back to this specific revision; 0 means "the immediately previous one"
 }
 
 // Workflow states
@@ -118,6 +414,7 @@ type WorkflowState struct {
 type DeployToKubernetesRequest struct {
 	ImageTag    string
 	Environment string // staging or production
+	KubeContext string // optional; selects a non-default cluster via KubernetesActivities.ClientResolver
 }
 
 type DeployToKubernetesResponse struct {
@@ -125,6 +422,7 @@ type DeployToKubernetesResponse struct {
 	DeploymentURL string
 	Message       string
 	Timestamp     time.Time
+	KubeContext   string // the kube-context this deploy actually ran against, resolved from ClusterTarget.Name when the caller didn't supply one directly
 }
 
 type CheckDeploymentStatusRequest struct {
@@ -138,7 +436,6 @@ type CheckDeploymentStatusResponse struct {
 	Message       string
 }
 
-
 type GetServiceURLRequest struct {
 	Environment string
 	ServiceName string
@@ -150,6 +447,34 @@ type GetServiceURLResponse struct {
 	Message string
 }
 
+// PortForwardRequest opens a `kubectl port-forward` tunnel to a Service that
+// has no externally reachable address yet (common on kind/minikube/private
+// clusters before a LoadBalancer ingress is assigned), so a later step like a
+// smoke test can still reach it over a loopback URL.
+type PortForwardRequest struct {
+	ServiceName string
+	Namespace   string
+	Environment string // used to resolve Namespace when Namespace is empty
+	TargetPort  int    // service port to forward to; defaults to 8080
+}
+
+type PortForwardResponse struct {
+	URL       string
+	LocalPort int
+}
+
+// StopPortForwardRequest tears down a tunnel previously opened by
+// PortForwardServiceURL, identified by the same service/namespace pair.
+type StopPortForwardRequest struct {
+	ServiceName string
+	Namespace   string
+	Environment string
+}
+
+type StopPortForwardResponse struct {
+	Stopped bool
+}
+
 // Additional Approval activity types
 type SendApprovalRequestRequest struct {
 	Environment string
@@ -158,9 +483,105 @@ type SendApprovalRequestRequest struct {
 }
 
 type SendApprovalRequestResponse struct {
-	Success        bool
-	NotificationID string
-	Message        string
+	Success          bool
+	NotificationID   string   // first entry of NotificationIDs; kept for callers that only ever expected one channel
+	NotificationIDs  []string // one per backend that accepted the request, when Kinds fanned out to several
+	PartialFailure   bool     // true if at least one configured channel failed even though the request still went out on another
+	CorrelationToken string   // verified by cmd/approval-server before it signals the workflow
+	Message          string
+}
+
+// ApprovalChannelKind selects which concrete approval backend a Notifier talks to.
+type ApprovalChannelKind string
+
+const (
+	ApprovalChannelSlack     ApprovalChannelKind = "slack"
+	ApprovalChannelGitHub    ApprovalChannelKind = "github"
+	ApprovalChannelEmail     ApprovalChannelKind = "email"
+	ApprovalChannelPagerDuty ApprovalChannelKind = "pagerduty"
+	ApprovalChannelWebhook   ApprovalChannelKind = "webhook"
+)
+
+// ApprovalChannelConfig configures whichever approval backend(s) are active.
+// Only the fields relevant to Kind/Kinds need to be populated.
+type ApprovalChannelConfig struct {
+	// Kind selects a single approval backend; kept for backward compatibility
+	// with callers that only ever needed one. Kinds, when non-empty, takes
+	// precedence and fans the same request out to every listed backend in
+	// parallel.
+	Kind  ApprovalChannelKind
+	Kinds []ApprovalChannelKind
+
+	// NotifierTimeout bounds each individual backend's Send call when fanning
+	// out to Kinds, so one slow/unreachable notifier can't stall the others.
+	// Defaults to 30s when zero.
+	NotifierTimeout time.Duration
+
+	// Slack
+	SlackWebhookURL string
+	SlackChannel    string
+
+	// GitHub deployment approvals
+	GitHubOwner       string
+	GitHubRepo        string
+	GitHubEnvironment string
+	GitHubToken       string
+
+	// Email
+	EmailTo    []string
+	EmailFrom  string
+	SMTPServer string
+
+	// PagerDuty Events API v2
+	PagerDutyRoutingKey string
+
+	// GenericWebhook posts a JSON payload to WebhookURL, HMAC-SHA256-signed
+	// with WebhookSecret (when set) in an X-Signature header so the receiver
+	// can verify it came from this pipeline.
+	WebhookURL    string
+	WebhookSecret string
+
+	// CallbackBaseURL is the public address of cmd/approval-server, embedded in
+	// approval links/buttons so a click can reach back to SignalWorkflow.
+	CallbackBaseURL string
+
+	// CallbackSigningSecret, when set, HMAC-SHA256-signs every approve/reject
+	// callback URL (sig=hex(hmac(secret, token))) so cmd/approval-server can
+	// reject forged/guessed tokens instead of trusting the token alone. Must
+	// match the APPROVAL_CALLBACK_SIGNING_SECRET cmd/approval-server is
+	// started with, or every approve/reject link gets rejected as unsigned.
+	CallbackSigningSecret string
+
+	// EscalationTimeout is how long deployToProduction waits for a decision before
+	// paging a secondary approver; EscalationTimeout*2 (approximately) before
+	// auto-rejecting.
+	EscalationTimeout time.Duration
+	SecondaryApprover string
+}
+
+// ApprovalCallbackRequest is what cmd/approval-server receives when an approver
+// clicks Approve/Reject, and what it verifies before signaling the workflow.
+type ApprovalCallbackRequest struct {
+	CorrelationToken string
+	WorkflowID       string
+	RunID            string
+	Approved         bool
+	Approver         string
+	Reason           string
+}
+
+// EscalateApprovalRequest pages a secondary approver when the primary approval
+// channel hasn't produced a decision within the configured timeout.
+type EscalateApprovalRequest struct {
+	Environment       string
+	ImageTag          string
+	WorkflowID        string
+	SecondaryApprover string
+}
+
+type EscalateApprovalResponse struct {
+	Success bool
+	Message string
 }
 
 type LogApprovalDecisionRequest struct {
@@ -195,4 +616,109 @@ type ApprovalSignal struct {
 	Reason   string
 }
 
+// Canary deployment types
+type CanaryStep struct {
+	WeightPercent int           // percentage of traffic/replicas sent to the canary
+	PauseDuration time.Duration // how long to bake at this weight before analysis
+}
+
+type CanaryConfig struct {
+	Steps              []CanaryStep
+	MetricsWindow      time.Duration // lookback window passed to the metrics provider
+	MaxErrorRate       float64       // canary error rate above this fails analysis
+	MaxLatencyP95Ms    float64       // canary P95 latency (ms) above this fails analysis
+	MinSuccessRate     float64       // canary success rate below this fails analysis
+	UseStatisticalTest bool          // compare canary vs baseline with a Mann-Whitney U / t-test in addition to thresholds
+}
+
+type MetricSnapshot struct {
+	SuccessRate  float64
+	LatencyP95Ms float64
+	ErrorRate    float64
+	SampleSize   int
+}
+
+type AnalyzeCanaryMetricsRequest struct {
+	DeploymentName string
+	Namespace      string
+	Window         time.Duration
+	Config         CanaryConfig
+}
+
+type AnalyzeCanaryMetricsResponse struct {
+	Passed   bool
+	Reason   string
+	Canary   MetricSnapshot
+	Baseline MetricSnapshot
+}
+
+type ScaleCanaryRequest struct {
+	DeploymentName string
+	Namespace      string
+	WeightPercent  int
+}
+
+type ScaleCanaryResponse struct {
+	Success       bool
+	WeightPercent int
+	Message       string
+}
+
+type RollbackResponse struct {
+	Success       bool
+	RestoredImage string
+	Revision      int64
+	Message       string
+}
 
+// PodDiagnosticsRequest asks CollectPodDiagnostics to gather everything a human
+// would reach for after a failed rollout: pod/container state, recent logs, and
+// events, all scoped to the Deployment's pods.
+type PodDiagnosticsRequest struct {
+	DeploymentName string
+	Namespace      string
+	LogLines       int64 // tail lines fetched per container; defaults to 10
+}
+
+type PodDiagnosticsResponse struct {
+	Pods   []PodDiagnostic
+	Events []PodEvent
+}
+
+// PodDiagnostic summarizes one pod's containers, roughly the information
+// `kubectl describe pod` prints.
+type PodDiagnostic struct {
+	Name       string
+	Phase      string
+	Containers []ContainerDiagnostic
+}
+
+// ContainerDiagnostic captures one container's current state plus its most
+// recent logs, and its previous-instance logs when it has restarted.
+type ContainerDiagnostic struct {
+	Name         string
+	Ready        bool
+	RestartCount int32
+	State        string // e.g. "running", "waiting: CrashLoopBackOff", "terminated: Error"
+	Logs         string
+	PreviousLogs string // populated only when RestartCount > 0
+}
+
+// PodEvent is a Kubernetes Event involving one of the diagnosed pods, ordered
+// by LastTimestamp across all pods.
+type PodEvent struct {
+	PodName       string
+	Type          string
+	Reason        string
+	Message       string
+	LastTimestamp time.Time
+}
+
+// CanaryState is exposed through the "canary-status" query handler
+type CanaryState struct {
+	CurrentStep   int
+	WeightPercent int
+	LastSnapshot  MetricSnapshot
+	Aborted       bool
+	AbortReason   string
+}
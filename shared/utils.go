@@ -1,8 +1,13 @@
 package shared
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -21,6 +26,18 @@ func FormatImageTag(registry, image, tag string) string {
 	return fmt.Sprintf("%s/%s:%s", registry, image, tag)
 }
 
+// FormatImageDigest formats an immutable, content-addressable image reference
+// from the digest PushToRegistry returns, e.g. "registry/image@sha256:...".
+// CICDPipelineWorkflow deploys this instead of the mutable tag so a rollout
+// can never silently pick up a different image than the one that was built,
+// tested, and attested.
+func FormatImageDigest(registry, image, digest string) string {
+	if registry == "" {
+		return fmt.Sprintf("%s@%s", image, digest)
+	}
+	return fmt.Sprintf("%s/%s@%s", registry, image, digest)
+}
+
 // IsProductionEnvironment checks if the environment is production
 func IsProductionEnvironment(env string) bool {
 	return env == "production" || env == "prod"
@@ -51,30 +68,50 @@ func SimulateFailure(probability float32, errorMsg string) error {
 	return nil
 }
 
-// IsWithinDeploymentWindow checks if current time is within deployment window
-func IsWithinDeploymentWindow(startHour, endHour int) bool {
-	now := time.Now()
-	currentHour := now.Hour()
-	
-	// Simple logic for demo - in production this would be more sophisticated
-	if startHour <= endHour {
-		return currentHour >= startHour && currentHour < endHour
+// Deployment window scheduling now lives in DeploymentSchedule (schedule.go),
+// which supports IANA timezones, per-weekday windows, and blackout dates instead
+// of the old fixed start/end-hour check.
+
+// SignCallbackToken HMAC-SHA256-signs payload with secret, hex-encoded. Used
+// both to sign approval callback URLs/webhook bodies (activities/approval.go)
+// and to verify them (cmd/approval-server), so an approve/reject link can't be
+// forged or guessed. Returns "" when secret is empty, meaning "unsigned".
+func SignCallbackToken(secret, payload string) string {
+	if secret == "" {
+		return ""
 	}
-	// Handle overnight windows (e.g., 22:00 - 02:00)
-	return currentHour >= startHour || currentHour < endHour
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// GetDeploymentWindowWaitTime calculates how long to wait for next window
-func GetDeploymentWindowWaitTime(startHour int) time.Duration {
-	now := time.Now()
-	currentHour := now.Hour()
-	
-	waitHours := startHour - currentHour
-	if waitHours <= 0 {
-		waitHours += 24
+// EncodeCorrelationToken packs a workflow ID and run ID into the opaque
+// correlation token handed to approval channels (Slack buttons, email links,
+// PagerDuty dedup keys), base64-encoding each field and joining them with "."
+// so the split on the way back out is unambiguous — unlike workflow/run IDs
+// themselves, "." never appears in base64url output.
+func EncodeCorrelationToken(workflowID, runID string) string {
+	return fmt.Sprintf("%s.%s.%d",
+		base64.RawURLEncoding.EncodeToString([]byte(workflowID)),
+		base64.RawURLEncoding.EncodeToString([]byte(runID)),
+		time.Now().Unix())
+}
+
+// DecodeCorrelationToken reverses EncodeCorrelationToken, the cmd/approval-server
+// counterpart that turns a callback's token query param back into the
+// workflow/run ID pair to signal.
+func DecodeCorrelationToken(token string) (workflowID, runID string, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	wfBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", false
 	}
-	
-	// For demo purposes, return seconds instead of hours
-	// In production, this would return actual hours
-	return time.Duration(waitHours) * time.Second
-}
\ No newline at end of file
+	runBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", "", false
+	}
+	return string(wfBytes), string(runBytes), true
+}
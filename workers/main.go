@@ -1,10 +1,18 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/temporal-community/workshop-cicd-k8s-deployment/activities"
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/runtime"
 	"github.com/temporal-community/workshop-cicd-k8s-deployment/workflows"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
@@ -20,6 +28,11 @@ func main() {
 	}
 	defer c.Close()
 
+	k8sClient, err := newKubernetesClient()
+	if err != nil {
+		log.Fatalf("Unable to create Kubernetes client: %v", err)
+	}
+
 	// Create worker
 	w := worker.New(c, "cicd-task-queue", worker.Options{})
 
@@ -27,31 +40,76 @@ func main() {
 	w.RegisterWorkflow(workflows.CICDPipelineWorkflow)
 
 	// Register Docker activities
-	w.RegisterActivity(activities.BuildDockerImage)
-	w.RegisterActivity(activities.TestDockerContainer)
-	w.RegisterActivity(activities.PushToRegistry)
+	dockerActivities := activities.NewDockerActivities(newContainerRuntime())
+	w.RegisterActivity(dockerActivities.BuildDockerImage)
+	w.RegisterActivity(dockerActivities.TestDockerContainer)
+	w.RegisterActivity(dockerActivities.RunPostCommitHook)
+	w.RegisterActivity(dockerActivities.PushToRegistry)
+
+	// Register Registry activities
+	registryActivities := &activities.RegistryActivities{}
+	w.RegisterActivity(registryActivities.RegistryLogin)
+	w.RegisterActivity(registryActivities.PullThroughCache)
 
 	// Register Kubernetes activities
-	k8sActivities := &activities.KubernetesActivities{}
+	k8sActivities := activities.NewKubernetesActivities(k8sClient, "")
+	k8sActivities.ClientResolver = newKubeContextClientResolver()
 	w.RegisterActivity(k8sActivities.DeployToKubernetes)
 	w.RegisterActivity(k8sActivities.CheckDeploymentStatus)
 	w.RegisterActivity(k8sActivities.GetServiceURL)
+	w.RegisterActivity(k8sActivities.RollbackDeployment)
+	w.RegisterActivity(k8sActivities.PortForwardServiceURL)
+	w.RegisterActivity(k8sActivities.StopPortForward)
+	w.RegisterActivity(k8sActivities.CollectPodDiagnostics)
+
+	// Register multi-cluster workflows and the kube-context resolver activity
+	// they depend on
+	w.RegisterWorkflow(workflows.MultiClusterDeployWorkflow)
+	w.RegisterWorkflow(workflows.DeployToClusterWorkflow)
+	kubeContextResolver := &activities.KubeContextResolver{}
+	w.RegisterActivity(kubeContextResolver.ResolveKubeContext)
+
+	// Register Monitoring activities
+	monitoringActivities := activities.NewMonitoringActivities(k8sClient)
+	w.RegisterActivity(monitoringActivities.ValidateDeployment)
 
 	// Register Approval activities
 	approvalActivities := &activities.ApprovalActivities{}
 	w.RegisterActivity(approvalActivities.SendApprovalRequest)
 	w.RegisterActivity(approvalActivities.LogApprovalDecision)
 	w.RegisterActivity(approvalActivities.SendApprovalNotification)
+	w.RegisterActivity(approvalActivities.EscalateApproval)
 
+	// Register Canary activities (progressive delivery for production deploys)
+	canaryActivities := activities.NewCanaryActivities(&activities.PrometheusMetricsProvider{}, k8sActivities)
+	w.RegisterActivity(canaryActivities.ScaleCanary)
+	w.RegisterActivity(canaryActivities.AnalyzeCanaryMetrics)
+
+	// Register Attestation activities (SBOM, SLSA provenance, cosign sign/verify)
+	cosignSigner := &activities.CosignSigner{
+		KeyRef:               os.Getenv("COSIGN_KEY_REF"),
+		CertIdentityRegexp:   os.Getenv("COSIGN_CERT_IDENTITY_REGEXP"),
+		CertOIDCIssuerRegexp: os.Getenv("COSIGN_CERT_OIDC_ISSUER_REGEXP"),
+	}
+	attestationActivities := activities.NewAttestationActivities(&activities.SyftScanner{}, cosignSigner)
+	w.RegisterActivity(attestationActivities.GenerateSBOM)
+	w.RegisterActivity(attestationActivities.GenerateProvenance)
+	w.RegisterActivity(attestationActivities.SignAndAttach)
+	w.RegisterActivity(attestationActivities.VerifyImagePolicy)
+	w.RegisterActivity(attestationActivities.ScanImage)
 
 	log.Println("Starting Temporal worker for CI/CD Pipeline")
 	log.Println("Worker listening on task queue: cicd-task-queue")
 	log.Println("Registered workflows:")
 	log.Println("  - CICDPipelineWorkflow (human-in-the-loop workflow)")
+	log.Println("  - MultiClusterDeployWorkflow, DeployToClusterWorkflow (multi-region fan-out)")
 	log.Println("Registered activities:")
-	log.Println("  - Docker: Build, Test, Push")
-	log.Println("  - Kubernetes: Deploy, CheckStatus, GetServiceURL")
+	log.Printf("  - Docker: Build, Test, RunPostCommitHook, Push (runtime: %s)", containerRuntimeName())
+	log.Println("  - Registry: Login, PullThroughCache")
+	log.Println("  - Kubernetes: Deploy, CheckStatus, GetServiceURL, PortForwardServiceURL, StopPortForward, CollectPodDiagnostics")
 	log.Println("  - Approval: SendRequest, LogDecision, SendNotification")
+	log.Println("  - KubeContext: ResolveKubeContext")
+	log.Println("  - Attestation: GenerateSBOM, GenerateProvenance, SignAndAttach, VerifyImagePolicy, ScanImage")
 
 	// Start worker
 	err = w.Run(worker.InterruptCh())
@@ -66,4 +124,93 @@ func getTemporalHost() string {
 		return "localhost:7233"
 	}
 	return host
-}
\ No newline at end of file
+}
+
+// containerRuntimeName returns the CONTAINER_RUNTIME value newContainerRuntime
+// will act on, defaulting to "docker" for the startup log.
+func containerRuntimeName() string {
+	if name := os.Getenv("CONTAINER_RUNTIME"); name != "" {
+		return name
+	}
+	return "docker"
+}
+
+// newContainerRuntime selects the ContainerRuntime backend based on
+// CONTAINER_RUNTIME, defaulting to the docker CLI/buildx for workshop use.
+func newContainerRuntime() runtime.ContainerRuntime {
+	switch containerRuntimeName() {
+	case "buildkit":
+		return &runtime.BuildKitRuntime{Addr: os.Getenv("BUILDKIT_ADDR")}
+	case "podman":
+		return &runtime.PodmanRuntime{}
+	case "nerdctl":
+		return &runtime.NerdctlRuntime{}
+	default:
+		return &runtime.DockerRuntime{}
+	}
+}
+
+// newKubernetesClient builds a typed client-go client, preferring in-cluster
+// config (when the worker itself runs in Kubernetes) and falling back to
+// ~/.kube/config or $KUBECONFIG for local/workshop use.
+func newKubernetesClient() (kubernetes.Interface, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		kubeconfig := os.Getenv("KUBECONFIG")
+		if kubeconfig == "" {
+			home, homeErr := os.UserHomeDir()
+			if homeErr == nil {
+				kubeconfig = filepath.Join(home, ".kube", "config")
+			}
+		}
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return kubernetes.NewForConfig(config)
+}
+
+// newKubeContextClientResolver returns a KubernetesActivities.ClientResolver
+// that builds a typed client for any context defined in the worker's local
+// kubeconfig. This is the workshop-scale counterpart to
+// activities.KubeContextResolver: it trusts that every target cluster is
+// already merged into $KUBECONFIG (or ~/.kube/config) under the resolved
+// context name, rather than fetching per-cluster credentials from a secret
+// store.
+func newKubeContextClientResolver() func(kubeContext string) (kubernetes.Interface, error) {
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+	}
+
+	var mu sync.Mutex
+	clients := make(map[string]kubernetes.Interface)
+	return func(kubeContext string) (kubernetes.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if client, ok := clients[kubeContext]; ok {
+			return client, nil
+		}
+
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+			&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+		).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kube context %q: %w", kubeContext, err)
+		}
+
+		client, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build client for kube context %q: %w", kubeContext, err)
+		}
+
+		clients[kubeContext] = client
+		return client, nil
+	}
+}
@@ -0,0 +1,196 @@
+package workflows
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// MultiClusterDeployWorkflow rolls the same image out to N clusters, deploying
+// clusters within a wave in parallel and waves in order (e.g. us-east -> eu-west
+// -> ap-south). It is invoked as a child workflow from CICDPipelineWorkflow
+// when PipelineRequest.Clusters is non-empty.
+func MultiClusterDeployWorkflow(ctx workflow.Context, req shared.MultiClusterDeployRequest) (*shared.MultiClusterDeployResponse, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting multi-cluster deploy", "clusters", len(req.Clusters), "imageTag", req.ImageTag)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	waves := groupByWave(req.Clusters)
+
+	result := &shared.MultiClusterDeployResponse{
+		Results: make(map[string]shared.DeployToKubernetesResponse),
+	}
+
+	for _, wave := range waves {
+		logger.Info("Deploying wave", "wave", wave.index, "clusters", len(wave.clusters))
+
+		failures := deployWave(ctx, logger, req, wave.clusters, result)
+
+		if failures > req.FailureBudget {
+			logger.Error("Wave exceeded failure budget, rolling back succeeded clusters",
+				"wave", wave.index, "failures", failures, "budget", req.FailureBudget)
+			rollbackSucceeded(ctx, logger, req.Environment, result)
+			return result, fmt.Errorf("wave %d exceeded failure budget (%d failures > budget %d)", wave.index, failures, req.FailureBudget)
+		}
+	}
+
+	logger.Info("Multi-cluster deploy completed", "succeeded", len(result.Succeeded), "failed", len(result.Failed))
+	return result, nil
+}
+
+// deployWave launches one child workflow per cluster in the wave, waits for all
+// of them to settle, and records each outcome into result. If more clusters
+// fail than the caller's FailureBudget allows, it cancels any still-outstanding
+// children in the wave before returning.
+func deployWave(ctx workflow.Context, logger log.Logger, req shared.MultiClusterDeployRequest, clusters []shared.ClusterTarget, result *shared.MultiClusterDeployResponse) int {
+	type pending struct {
+		cluster shared.ClusterTarget
+		future  workflow.ChildWorkflowFuture
+		cancel  workflow.CancelFunc
+	}
+
+	children := make([]pending, 0, len(clusters))
+	for _, cluster := range clusters {
+		childCtx, cancel := workflow.WithCancel(ctx)
+		cwo := workflow.ChildWorkflowOptions{
+			WorkflowID: fmt.Sprintf("%s-%s", workflow.GetInfo(ctx).WorkflowExecution.ID, cluster.Name),
+		}
+		childCtx = workflow.WithChildOptions(childCtx, cwo)
+		future := workflow.ExecuteChildWorkflow(childCtx, DeployToClusterWorkflow, req.ImageTag, req.Environment, cluster)
+		children = append(children, pending{cluster: cluster, future: future, cancel: cancel})
+	}
+
+	failures := 0
+	for _, child := range children {
+		var resp shared.DeployToKubernetesResponse
+		err := child.future.Get(ctx, &resp)
+		if err != nil {
+			logger.Error("Cluster deploy failed", "cluster", child.cluster.Name, "error", err)
+			failures++
+			result.Failed = append(result.Failed, child.cluster.Name)
+
+			if failures > req.FailureBudget {
+				for _, other := range children {
+					if other.cluster.Name != child.cluster.Name {
+						other.cancel()
+					}
+				}
+			}
+			continue
+		}
+
+		result.Results[child.cluster.Name] = resp
+		result.Succeeded = append(result.Succeeded, child.cluster.Name)
+	}
+
+	return failures
+}
+
+// rollbackSucceeded invokes RollbackDeployment for every cluster that had
+// already succeeded by the time the wave's failure budget was exceeded,
+// against the kube-context DeployToClusterWorkflow actually resolved and
+// deployed to (result.Results[name].KubeContext) — not the original
+// ClusterTarget, whose KubeContext is empty whenever it was resolved by name
+// via ResolveKubeContext rather than supplied directly.
+func rollbackSucceeded(ctx workflow.Context, logger log.Logger, environment string, result *shared.MultiClusterDeployResponse) {
+	disconnectedCtx, _ := workflow.NewDisconnectedContext(ctx)
+	for _, clusterName := range result.Succeeded {
+		deployResp, ok := result.Results[clusterName]
+		if !ok {
+			logger.Error("Failed to roll back cluster after wave failure", "cluster", clusterName, "error", fmt.Errorf("no recorded deploy result for cluster %q", clusterName))
+			continue
+		}
+		rollbackReq := shared.RollbackRequest{
+			DeploymentName: "demo-app",
+			Namespace:      shared.GetNamespaceForEnvironment(environment),
+			KubeContext:    deployResp.KubeContext,
+		}
+		var rollbackResp shared.RollbackResponse
+		if err := workflow.ExecuteActivity(disconnectedCtx, "RollbackDeployment", rollbackReq).Get(disconnectedCtx, &rollbackResp); err != nil {
+			logger.Error("Failed to roll back cluster after wave failure", "cluster", clusterName, "error", err)
+		}
+	}
+}
+
+// DeployToClusterWorkflow resolves the target cluster's kube-context and
+// deploys a single cluster. It is executed as a child workflow per cluster so
+// MultiClusterDeployWorkflow can cancel individual clusters independently.
+func DeployToClusterWorkflow(ctx workflow.Context, imageTag, environment string, cluster shared.ClusterTarget) (*shared.DeployToKubernetesResponse, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Deploying to cluster", "cluster", cluster.Name, "region", cluster.Region, "wave", cluster.Wave)
+
+	ao := workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    3,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, ao)
+
+	kubeContext := cluster.KubeContext
+	if kubeContext == "" {
+		var resolveResp shared.KubeContextResolverResponse
+		resolveReq := shared.KubeContextResolverRequest{ClusterName: cluster.Name}
+		if err := workflow.ExecuteActivity(ctx, "ResolveKubeContext", resolveReq).Get(ctx, &resolveResp); err != nil {
+			return nil, fmt.Errorf("failed to resolve kube context for cluster %s: %w", cluster.Name, err)
+		}
+		kubeContext = resolveResp.KubeContext
+	}
+
+	deployReq := shared.DeployToKubernetesRequest{
+		ImageTag:    imageTag,
+		Environment: environment,
+		KubeContext: kubeContext,
+	}
+
+	var deployResp shared.DeployToKubernetesResponse
+	if err := workflow.ExecuteActivity(ctx, "DeployToKubernetes", deployReq).Get(ctx, &deployResp); err != nil {
+		return nil, fmt.Errorf("deploy to cluster %s failed: %w", cluster.Name, err)
+	}
+
+	return &deployResp, nil
+}
+
+type clusterWave struct {
+	index    int
+	clusters []shared.ClusterTarget
+}
+
+// groupByWave buckets clusters by Wave and returns the buckets sorted by wave
+// index ascending, so callers can deploy them in order.
+func groupByWave(clusters []shared.ClusterTarget) []clusterWave {
+	byWave := make(map[int][]shared.ClusterTarget)
+	for _, c := range clusters {
+		byWave[c.Wave] = append(byWave[c.Wave], c)
+	}
+
+	waveIndexes := make([]int, 0, len(byWave))
+	for wave := range byWave {
+		waveIndexes = append(waveIndexes, wave)
+	}
+	sort.Ints(waveIndexes)
+
+	waves := make([]clusterWave, 0, len(waveIndexes))
+	for _, idx := range waveIndexes {
+		waves = append(waves, clusterWave{index: idx, clusters: byWave[idx]})
+	}
+	return waves
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/temporal-community/workshop-cicd-k8s-deployment/activities"
 	"github.com/temporal-community/workshop-cicd-k8s-deployment/shared"
 	"go.temporal.io/sdk/log"
 	"go.temporal.io/sdk/temporal"
@@ -35,72 +34,165 @@ func CICDPipelineWorkflow(ctx workflow.Context, request shared.PipelineRequest)
 	// Phase 1: Build, Test, and Push (always happens)
 	logger.Info("Phase 1: Docker build, test, and push")
 
-	// Step 1: Build Docker image
-	logger.Info("Starting Docker build")
-	var buildResp shared.DockerBuildResponse
-	buildReq := shared.DockerBuildRequest{
-		ImageName:    request.ImageName,
-		Tag:          request.Tag,
-		BuildContext: request.BuildContext,
-		Dockerfile:   request.Dockerfile,
-	}
-
-	err := workflow.ExecuteActivity(ctx, activities.BuildDockerImage, buildReq).Get(ctx, &buildResp)
-	if err != nil {
-		logger.Error("Docker build failed", "error", err)
-		return fmt.Errorf("docker build failed: %w", err)
+	// Validate registry credentials before the build so a bad secret fails
+	// fast instead of surfacing midway through the push. Skipped when no
+	// registry is configured (the workshop's default local demo flow), the
+	// same condition PullThroughCache below already tolerates.
+	if request.RegistryURL != "" {
+		loginCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+			StartToCloseTimeout: time.Minute,
+			RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+		})
+		var loginResp shared.RegistryLoginResponse
+		loginReq := shared.RegistryLoginRequest{
+			RegistryURL: request.RegistryURL,
+			Auth:        request.RegistryAuth,
+		}
+		if err := workflow.ExecuteActivity(loginCtx, "RegistryLogin", loginReq).Get(loginCtx, &loginResp); err != nil {
+			logger.Error("Registry login failed", "error", err)
+			return fmt.Errorf("registry login failed: %w", err)
+		}
+	} else {
+		logger.Info("No registry configured, skipping registry login")
 	}
-	logger.Info("Docker build completed",
-		"imageID", buildResp.ImageID,
-		"duration", buildResp.BuildTime)
 
-	// Step 2: Test Docker container
-	logger.Info("Starting Docker tests")
-	var testResp shared.DockerTestResponse
-	testReq := shared.DockerTestRequest{
+	// Cache gate: skip the build/test/push phase entirely if this exact tag
+	// is already present in the target registry or one of its mirrors.
+	logger.Info("Checking pull-through cache before building")
+	var cacheResp shared.PullThroughCacheResponse
+	cacheReq := shared.PullThroughCacheRequest{
 		ImageName: request.ImageName,
 		Tag:       request.Tag,
+		Registry: shared.RegistryConfig{
+			RegistryURL: request.RegistryURL,
+			Mirrors:     request.RegistryMirrors,
+			Insecure:    request.InsecureRegistry,
+		},
+		Auth: request.RegistryAuth,
 	}
-
-	err = workflow.ExecuteActivity(ctx, activities.TestDockerContainer, testReq).Get(ctx, &testResp)
-	if err != nil {
-		logger.Error("Docker tests failed", "error", err)
-		return fmt.Errorf("docker tests failed: %w", err)
+	if err := workflow.ExecuteActivity(ctx, "PullThroughCache", cacheReq).Get(ctx, &cacheResp); err != nil {
+		logger.Error("Pull-through cache check failed", "error", err)
+		return fmt.Errorf("pull-through cache check failed: %w", err)
 	}
-	logger.Info("Docker tests completed",
-		"passed", testResp.Passed,
-		"duration", testResp.TestTime)
 
-	if !testResp.Passed {
-		return fmt.Errorf("docker tests failed: %s", testResp.Output)
-	}
+	// registryURL tracks whichever registry the image actually lives in, so
+	// the deploy ref below always points at a registry that really has the
+	// digest: the primary on a fresh push, or cacheResp.RegistryURL when a
+	// mirror (checked before the primary by PullThroughCache) satisfied the
+	// cache hit instead.
+	registryURL := request.RegistryURL
 
-	// Step 3: Push to registry
-	logger.Info("Starting Docker push")
 	var pushResp shared.DockerPushResponse
-	pushReq := shared.DockerPushRequest{
-		ImageName:    request.ImageName,
-		Tag:          request.Tag,
-		RegistryURL:  request.RegistryURL,
-		BuildContext: request.BuildContext,
-		Dockerfile:   request.Dockerfile,
-	}
+	if cacheResp.Found {
+		logger.Info("Image already present, skipping build/test/push",
+			"registry", cacheResp.RegistryURL,
+			"digest", cacheResp.Digest)
+		pushResp.Digest = cacheResp.Digest
+		registryURL = cacheResp.RegistryURL
+	} else {
+		// Step 1: Build Docker image
+		logger.Info("Starting Docker build")
+		var buildResp shared.DockerBuildResponse
+		buildReq := shared.DockerBuildRequest{
+			ImageName:    request.ImageName,
+			Tag:          request.Tag,
+			BuildContext: request.BuildContext,
+			Dockerfile:   request.Dockerfile,
+		}
 
-	err = workflow.ExecuteActivity(ctx, activities.PushToRegistry, pushReq).Get(ctx, &pushResp)
-	if err != nil {
-		logger.Error("Docker push failed", "error", err)
-		return fmt.Errorf("docker push failed: %w", err)
+		if err := workflow.ExecuteActivity(ctx, "BuildDockerImage", buildReq).Get(ctx, &buildResp); err != nil {
+			logger.Error("Docker build failed", "error", err)
+			return fmt.Errorf("docker build failed: %w", err)
+		}
+		logger.Info("Docker build completed",
+			"imageID", buildResp.ImageID,
+			"duration", buildResp.BuildTime)
+
+		// Step 2: Test Docker container
+		logger.Info("Starting Docker tests")
+		var testResp shared.DockerTestResponse
+		testReq := shared.DockerTestRequest{
+			ImageName: request.ImageName,
+			Tag:       request.Tag,
+		}
+
+		if err := workflow.ExecuteActivity(ctx, "TestDockerContainer", testReq).Get(ctx, &testResp); err != nil {
+			logger.Error("Docker tests failed", "error", err)
+			return fmt.Errorf("docker tests failed: %w", err)
+		}
+		logger.Info("Docker tests completed",
+			"passed", testResp.Passed,
+			"duration", testResp.TestTime)
+
+		if !testResp.Passed {
+			return fmt.Errorf("docker tests failed: %s", testResp.Output)
+		}
+
+		// Step 2b: Post-commit hook (optional project-specific smoke test)
+		if request.PostCommitHook.Script != "" || len(request.PostCommitHook.Command) > 0 {
+			logger.Info("Running post-commit hook")
+			hookCtx := ctx
+			if request.PostCommitHook.FailBuildOnError {
+				hookCtx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+					StartToCloseTimeout: 10 * time.Minute,
+					RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+				})
+			}
+
+			var hookResp shared.PostCommitHookResponse
+			hookReq := shared.PostCommitHookRequest{
+				ImageName:        request.ImageName,
+				Tag:              request.Tag,
+				Script:           request.PostCommitHook.Script,
+				Command:          request.PostCommitHook.Command,
+				Args:             request.PostCommitHook.Args,
+				Env:              request.PostCommitHook.Env,
+				FailBuildOnError: request.PostCommitHook.FailBuildOnError,
+			}
+			if err := workflow.ExecuteActivity(hookCtx, "RunPostCommitHook", hookReq).Get(hookCtx, &hookResp); err != nil {
+				logger.Error("Post-commit hook failed", "error", err)
+				return fmt.Errorf("post-commit hook failed: %w", err)
+			}
+			logger.Info("Post-commit hook completed", "passed", hookResp.Passed, "exitCode", hookResp.ExitCode)
+		}
+
+		// Step 2c: Pre-push vulnerability scan (optional)
+		if request.PrePushScan.Enabled {
+			if err := scanBeforePush(ctx, logger, request.ImageName, request.Tag, request.PrePushScan); err != nil {
+				return err
+			}
+		}
+
+		// Step 3: Push to registry
+		logger.Info("Starting Docker push")
+		pushReq := shared.DockerPushRequest{
+			ImageName:    request.ImageName,
+			Tag:          request.Tag,
+			RegistryURL:  request.RegistryURL,
+			BuildContext: request.BuildContext,
+			Dockerfile:   request.Dockerfile,
+			Auth:         request.RegistryAuth,
+		}
+
+		if err := workflow.ExecuteActivity(ctx, "PushToRegistry", pushReq).Get(ctx, &pushResp); err != nil {
+			logger.Error("Docker push failed", "error", err)
+			return fmt.Errorf("docker push failed: %w", err)
+		}
+		logger.Info("Docker push completed",
+			"digest", pushResp.Digest,
+			"duration", pushResp.PushTime)
 	}
-	logger.Info("Docker push completed",
-		"digest", pushResp.Digest,
-		"duration", pushResp.PushTime)
 
-	// Construct full image path with registry
-	var fullImagePath string
-	if request.RegistryURL != "" {
-		fullImagePath = fmt.Sprintf("%s/%s:%s", request.RegistryURL, request.ImageName, request.Tag)
-	} else {
-		fullImagePath = fmt.Sprintf("%s:%s", request.ImageName, request.Tag)
+	// Deploys resolve to the immutable content-addressable digest, not the
+	// mutable tag, so a rollout can never pick up a different image than the
+	// one that was just built, tested, and (optionally) attested.
+	imageRef := shared.FormatImageDigest(registryURL, request.ImageName, pushResp.Digest)
+
+	if request.Attestation.Enabled {
+		logger.Info("Phase 1b: Generating SBOM, SLSA provenance, and signing image")
+		if err := attestImage(ctx, logger, imageRef, request.Attestation); err != nil {
+			return err
+		}
 	}
 
 	// Phase 2: Deploy to Staging (happens for staging and production environments)
@@ -108,14 +200,15 @@ func CICDPipelineWorkflow(ctx workflow.Context, request shared.PipelineRequest)
 		logger.Info("Phase 2: Deploying to staging environment")
 
 		deployReq := shared.DeployToKubernetesRequest{
-			ImageTag:    fullImagePath,
+			ImageTag:    imageRef,
 			Environment: "staging",
 		}
 
 		var deployResp shared.DeployToKubernetesResponse
-		err = workflow.ExecuteActivity(ctx, "DeployToKubernetes", deployReq).Get(ctx, &deployResp)
+		err := workflow.ExecuteActivity(ctx, "DeployToKubernetes", deployReq).Get(ctx, &deployResp)
 		if err != nil {
 			logger.Error("Staging deployment failed", "error", err)
+			collectPodDiagnostics(ctx, logger, "demo-app", "staging")
 			return fmt.Errorf("staging deployment failed: %w", err)
 		}
 
@@ -123,7 +216,7 @@ func CICDPipelineWorkflow(ctx workflow.Context, request shared.PipelineRequest)
 
 		// Phase 3: Production deployment with approval (if production environment)
 		if request.Environment == "production" {
-			err = deployToProduction(ctx, logger, fullImagePath, deployResp.DeploymentURL)
+			err = deployToProduction(ctx, logger, imageRef, deployResp.DeploymentURL, request.Canary, request.Schedule, request.Approval, request.Clusters, request.ClusterFailureBudget, request.Attestation)
 			if err != nil {
 				return err
 			}
@@ -137,28 +230,43 @@ func CICDPipelineWorkflow(ctx workflow.Context, request shared.PipelineRequest)
 }
 
 // deployToProduction handles the production deployment with approval
-func deployToProduction(ctx workflow.Context, logger log.Logger, fullImagePath, stagingURL string) error {
+func deployToProduction(ctx workflow.Context, logger log.Logger, imageRef, stagingURL string, canaryConfig shared.CanaryConfig, schedule *shared.DeploymentSchedule, approvalConfig shared.ApprovalChannelConfig, clusters []shared.ClusterTarget, clusterFailureBudget int, attestationConfig shared.AttestationConfig) error {
+	if attestationConfig.Enabled {
+		logger.Info("Phase 3a: Verifying image signature and SBOM policy before promotion")
+		if err := verifyImagePolicy(ctx, logger, imageRef, attestationConfig.Policy); err != nil {
+			return err
+		}
+	}
+
 	logger.Info("Phase 3: Requesting approval for production deployment")
 
 	// Send approval request
 	approvalReq := shared.SendApprovalRequestRequest{
 		Environment: "production",
-		ImageTag:    fullImagePath,
+		ImageTag:    imageRef,
 		StagingURL:  stagingURL,
 	}
 
 	var approvalResp shared.SendApprovalRequestResponse
-	err := workflow.ExecuteActivity(ctx, "SendApprovalRequest", approvalReq).Get(ctx, &approvalResp)
+	err := workflow.ExecuteActivity(ctx, "SendApprovalRequest", approvalReq, approvalConfig).Get(ctx, &approvalResp)
 	if err != nil {
 		logger.Error("Failed to send approval request", "error", err)
 		return fmt.Errorf("failed to send approval request: %w", err)
 	}
+	if approvalResp.PartialFailure {
+		// Not fatal: at least one channel got the request through, but an
+		// operator should know a configured notifier is broken before they
+		// depend on it (e.g. during an escalation).
+		logger.Warn("Approval request failed on some configured channels", "message", approvalResp.Message)
+	}
 
-	// Wait for approval signal
+	// Wait for approval signal, escalating to a secondary approver (and eventually
+	// auto-rejecting) so the pipeline doesn't hang forever on a silent channel.
 	logger.Info("Waiting for approval decision...")
-	approvalChannel := workflow.GetSignalChannel(ctx, "approval")
-	var approvalSignal shared.ApprovalSignal
-	approvalChannel.Receive(ctx, &approvalSignal)
+	approvalSignal, err := waitForApprovalDecision(ctx, logger, imageRef, approvalConfig)
+	if err != nil {
+		return err
+	}
 
 	// Log the approval decision
 	logReq := shared.LogApprovalDecisionRequest{
@@ -183,22 +291,394 @@ func deployToProduction(ctx workflow.Context, logger log.Logger, fullImagePath,
 			approvalSignal.Approver, approvalSignal.Reason)
 	}
 
+	if err := waitForDeploymentWindow(ctx, logger, schedule); err != nil {
+		return err
+	}
+
 	// Phase 4: Deploy to Production
 	logger.Info("Phase 4: Deploying to production environment")
 
+	if len(clusters) > 0 {
+		return deployMultiCluster(ctx, logger, imageRef, clusters, clusterFailureBudget)
+	}
+
 	prodDeployReq := shared.DeployToKubernetesRequest{
-		ImageTag:    fullImagePath,
+		ImageTag:    imageRef,
 		Environment: "production",
 	}
 
+	// The previous revision is still in the Deployment's rollout history at this
+	// point, so if the rollout's own health check fails below, automatically
+	// roll back to it instead of leaving production on a half-applied rollout.
 	var prodDeployResp shared.DeployToKubernetesResponse
-	err = workflow.ExecuteActivity(ctx, "DeployToKubernetes", prodDeployReq).Get(ctx, &prodDeployResp)
-	if err != nil {
-		logger.Error("Production deployment failed", "error", err)
-		return fmt.Errorf("production deployment failed: %w", err)
+	deployErr := func() (err error) {
+		defer func() {
+			if err != nil {
+				rollbackProduction(ctx, logger, "demo-app", err.Error())
+			}
+		}()
+		return workflow.ExecuteActivity(ctx, "DeployToKubernetes", prodDeployReq).Get(ctx, &prodDeployResp)
+	}()
+	if deployErr != nil {
+		logger.Error("Production deployment failed", "error", deployErr)
+		collectPodDiagnostics(ctx, logger, "demo-app", "production")
+		return fmt.Errorf("production deployment failed: %w", deployErr)
 	}
 
 	logger.Info("Production deployment successful", "url", prodDeployResp.DeploymentURL)
 
+	if len(canaryConfig.Steps) > 0 {
+		return runCanaryRollout(ctx, logger, "demo-app", "production", canaryConfig)
+	}
+
+	return nil
+}
+
+// deployMultiCluster hands production deployment off to MultiClusterDeployWorkflow
+// as a child workflow when the pipeline request targets more than one cluster.
+func deployMultiCluster(ctx workflow.Context, logger log.Logger, imageRef string, clusters []shared.ClusterTarget, failureBudget int) error {
+	cwo := workflow.ChildWorkflowOptions{
+		WorkflowID: workflow.GetInfo(ctx).WorkflowExecution.ID + "-multicluster",
+	}
+	childCtx := workflow.WithChildOptions(ctx, cwo)
+
+	req := shared.MultiClusterDeployRequest{
+		ImageTag:      imageRef,
+		Environment:   "production",
+		Clusters:      clusters,
+		FailureBudget: failureBudget,
+	}
+
+	var resp shared.MultiClusterDeployResponse
+	if err := workflow.ExecuteChildWorkflow(childCtx, MultiClusterDeployWorkflow, req).Get(childCtx, &resp); err != nil {
+		logger.Error("Multi-cluster production deployment failed", "error", err)
+		return fmt.Errorf("multi-cluster production deployment failed: %w", err)
+	}
+
+	logger.Info("Multi-cluster production deployment successful", "succeeded", resp.Succeeded)
+	return nil
+}
+
+// scanBeforePush scans the locally built image for vulnerabilities and fails
+// the pipeline before PushToRegistry ever runs if the findings exceed
+// config.MaxSeverity, so a vulnerable image is never published. This is
+// distinct from verifyImagePolicy, which re-scans the pushed, digest-addressed
+// image before production promotion.
+func scanBeforePush(ctx workflow.Context, logger log.Logger, imageName, tag string, config shared.PrePushScanConfig) error {
+	imageRef := shared.FormatImageTag("", imageName, tag)
+
+	scanCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+
+	var scanResp shared.ScanImageResponse
+	scanReq := shared.ScanImageRequest{ImageRef: imageRef, MaxSeverity: config.MaxSeverity}
+	if err := workflow.ExecuteActivity(scanCtx, "ScanImage", scanReq).Get(scanCtx, &scanResp); err != nil {
+		return fmt.Errorf("pre-push vulnerability scan failed: %w", err)
+	}
+
+	counts := scanResp.Report.Counts
+	logger.Info("Pre-push vulnerability scan completed",
+		"critical", counts.Critical, "high", counts.High, "medium", counts.Medium, "low", counts.Low,
+		"passed", scanResp.Passed)
+
+	if !scanResp.Passed {
+		return &shared.PolicyViolation{
+			ImageRef:   imageRef,
+			Violations: []string{fmt.Sprintf("vulnerability scan exceeded max severity %q (critical=%d, high=%d, medium=%d, low=%d)", config.MaxSeverity, counts.Critical, counts.High, counts.Medium, counts.Low)},
+		}
+	}
+	return nil
+}
+
+// attestImage generates an SBOM and SLSA provenance statement for imageRef and
+// signs both, along with the image itself, via the AttestationActivities.
+func attestImage(ctx workflow.Context, logger log.Logger, imageRef string, config shared.AttestationConfig) error {
+	var sbomResp shared.GenerateSBOMResponse
+	sbomReq := shared.GenerateSBOMRequest{ImageRef: imageRef, Format: "cyclonedx"}
+	if err := workflow.ExecuteActivity(ctx, "GenerateSBOM", sbomReq).Get(ctx, &sbomResp); err != nil {
+		return fmt.Errorf("failed to generate SBOM for %s: %w", imageRef, err)
+	}
+
+	var provenanceResp shared.GenerateProvenanceResponse
+	provenanceReq := shared.GenerateProvenanceRequest{
+		ImageRef:   imageRef,
+		WorkflowID: workflow.GetInfo(ctx).WorkflowExecution.ID,
+		RunID:      workflow.GetInfo(ctx).WorkflowExecution.RunID,
+		GitCommit:  config.GitCommit,
+		BuilderID:  config.BuilderID,
+	}
+	if err := workflow.ExecuteActivity(ctx, "GenerateProvenance", provenanceReq).Get(ctx, &provenanceResp); err != nil {
+		return fmt.Errorf("failed to generate provenance for %s: %w", imageRef, err)
+	}
+
+	signReq := shared.SignAndAttachRequest{
+		ImageRef:   imageRef,
+		SBOM:       sbomResp.SBOM,
+		Provenance: provenanceResp.Statement,
+	}
+	var signResp shared.SignAndAttachResponse
+	if err := workflow.ExecuteActivity(ctx, "SignAndAttach", signReq).Get(ctx, &signResp); err != nil {
+		return fmt.Errorf("failed to sign and attach attestations for %s: %w", imageRef, err)
+	}
+
+	logger.Info("Image signed and attested", "image", imageRef)
+	return nil
+}
+
+// verifyImagePolicy checks imageRef's cosign signature and SBOM against
+// policy before production promotion, failing with a structured
+// shared.PolicyViolation if either check fails.
+func verifyImagePolicy(ctx workflow.Context, logger log.Logger, imageRef string, policy shared.SBOMPolicy) error {
+	var verifyResp shared.VerifyImagePolicyResponse
+	verifyReq := shared.VerifyImagePolicyRequest{ImageRef: imageRef, Policy: policy}
+	if err := workflow.ExecuteActivity(ctx, "VerifyImagePolicy", verifyReq).Get(ctx, &verifyResp); err != nil {
+		return fmt.Errorf("failed to verify image policy for %s: %w", imageRef, err)
+	}
+
+	if !verifyResp.Passed {
+		logger.Error("Image failed signature/SBOM policy", "image", imageRef, "violations", verifyResp.Violations)
+		return &shared.PolicyViolation{ImageRef: imageRef, Violations: verifyResp.Violations}
+	}
+
+	logger.Info("Image passed signature/SBOM policy", "image", imageRef)
+	return nil
+}
+
+// collectPodDiagnostics runs CollectPodDiagnostics as a best-effort, non-retryable
+// step after a failed deploy, logging pod/container state and recent events so
+// they end up in the workflow's Temporal history alongside the deploy error.
+// It never fails the workflow itself: a diagnostics error is logged and swallowed.
+func collectPodDiagnostics(ctx workflow.Context, logger log.Logger, deploymentName, namespace string) {
+	diagCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	})
+
+	var diagResp shared.PodDiagnosticsResponse
+	diagReq := shared.PodDiagnosticsRequest{DeploymentName: deploymentName, Namespace: namespace}
+	if err := workflow.ExecuteActivity(diagCtx, "CollectPodDiagnostics", diagReq).Get(diagCtx, &diagResp); err != nil {
+		logger.Error("Failed to collect pod diagnostics", "deployment", deploymentName, "namespace", namespace, "error", err)
+		return
+	}
+
+	for _, pod := range diagResp.Pods {
+		logger.Error("Pod diagnostics", "pod", pod.Name, "phase", pod.Phase)
+		for _, container := range pod.Containers {
+			logger.Error("Container diagnostics",
+				"pod", pod.Name, "container", container.Name, "state", container.State,
+				"restarts", container.RestartCount, "logs", container.Logs)
+		}
+	}
+	for _, event := range diagResp.Events {
+		logger.Error("Pod event", "pod", event.PodName, "reason", event.Reason, "message", event.Message)
+	}
+}
+
+// runCanaryRollout progressively scales the production canary through the configured
+// weight steps, analyzing metrics at each pause and rolling back on failure or on
+// receipt of the "abort-canary" signal.
+func runCanaryRollout(ctx workflow.Context, logger log.Logger, deploymentName, namespace string, config shared.CanaryConfig) error {
+	state := shared.CanaryState{}
+
+	err := workflow.SetQueryHandler(ctx, "canary-status", func() (shared.CanaryState, error) {
+		return state, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register canary-status query handler: %w", err)
+	}
+
+	abortChannel := workflow.GetSignalChannel(ctx, "abort-canary")
+
+	for i, step := range config.Steps {
+		state.CurrentStep = i
+		state.WeightPercent = step.WeightPercent
+
+		logger.Info("Canary step: scaling", "step", i, "weight", step.WeightPercent)
+
+		var scaleResp shared.ScaleCanaryResponse
+		scaleReq := shared.ScaleCanaryRequest{
+			DeploymentName: deploymentName,
+			Namespace:      namespace,
+			WeightPercent:  step.WeightPercent,
+		}
+		if err := workflow.ExecuteActivity(ctx, "ScaleCanary", scaleReq).Get(ctx, &scaleResp); err != nil {
+			return rollbackCanary(ctx, logger, deploymentName, namespace, &state, fmt.Sprintf("failed to scale canary to %d%%: %v", step.WeightPercent, err))
+		}
+
+		// Bake at this weight, but wake early if an operator aborts the rollout.
+		var abortSignal struct{}
+		pauseTimer := workflow.NewTimer(ctx, step.PauseDuration)
+		selector := workflow.NewSelector(ctx)
+		aborted := false
+		selector.AddFuture(pauseTimer, func(f workflow.Future) {})
+		selector.AddReceive(abortChannel, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, &abortSignal)
+			aborted = true
+		})
+		selector.Select(ctx)
+
+		if aborted {
+			return rollbackCanary(ctx, logger, deploymentName, namespace, &state, "abort-canary signal received")
+		}
+
+		var analysis shared.AnalyzeCanaryMetricsResponse
+		analysisReq := shared.AnalyzeCanaryMetricsRequest{
+			DeploymentName: deploymentName,
+			Namespace:      namespace,
+			Window:         config.MetricsWindow,
+			Config:         config,
+		}
+		if err := workflow.ExecuteActivity(ctx, "AnalyzeCanaryMetrics", analysisReq).Get(ctx, &analysis); err != nil {
+			return rollbackCanary(ctx, logger, deploymentName, namespace, &state, fmt.Sprintf("canary analysis activity failed: %v", err))
+		}
+
+		state.LastSnapshot = analysis.Canary
+		if !analysis.Passed {
+			return rollbackCanary(ctx, logger, deploymentName, namespace, &state, analysis.Reason)
+		}
+
+		logger.Info("Canary step passed analysis", "step", i, "weight", step.WeightPercent)
+	}
+
+	logger.Info("Canary rollout completed successfully")
 	return nil
 }
+
+// rollbackCanary records the abort reason on the queryable state and invokes
+// RollbackDeployment, returning a structured error describing why the rollout failed.
+func rollbackCanary(ctx workflow.Context, logger log.Logger, deploymentName, namespace string, state *shared.CanaryState, reason string) error {
+	logger.Error("Aborting canary rollout", "reason", reason)
+	state.Aborted = true
+	state.AbortReason = reason
+
+	rollbackReq := shared.RollbackRequest{
+		DeploymentName: deploymentName,
+		Namespace:      namespace,
+	}
+
+	var rollbackResp shared.RollbackResponse
+	rollbackCtx, _ := workflow.NewDisconnectedContext(ctx)
+	if err := workflow.ExecuteActivity(rollbackCtx, "RollbackDeployment", rollbackReq).Get(rollbackCtx, &rollbackResp); err != nil {
+		logger.Error("Rollback itself failed", "error", err)
+		return fmt.Errorf("canary aborted (%s) and rollback failed: %w", reason, err)
+	}
+
+	return fmt.Errorf("canary rollout aborted: %s", reason)
+}
+
+// rollbackProduction invokes RollbackDeployment on a disconnected context, so
+// it still runs even if ctx itself is what's cancelled, when the initial
+// (non-canary) production rollout fails its own health check. Canary failures
+// roll back through rollbackCanary instead, which carries the step's abort
+// reason; this path only covers the plain deploy-to-production case.
+func rollbackProduction(ctx workflow.Context, logger log.Logger, deploymentName, reason string) {
+	logger.Error("Automatically rolling back production deployment", "reason", reason)
+
+	rollbackReq := shared.RollbackRequest{
+		DeploymentName: deploymentName,
+		Namespace:      "production",
+	}
+
+	var rollbackResp shared.RollbackResponse
+	rollbackCtx, _ := workflow.NewDisconnectedContext(ctx)
+	if err := workflow.ExecuteActivity(rollbackCtx, "RollbackDeployment", rollbackReq).Get(rollbackCtx, &rollbackResp); err != nil {
+		logger.Error("Automatic rollback failed", "error", err)
+		return
+	}
+
+	logger.Info("Automatic rollback completed", "restoredImage", rollbackResp.RestoredImage, "revision", rollbackResp.Revision)
+}
+
+// waitForDeploymentWindow sleeps deterministically until the schedule's next
+// allowed deployment window, unless an "override-window" signal arrives first.
+// The signal's approver identity is logged so overrides remain auditable.
+func waitForDeploymentWindow(ctx workflow.Context, logger log.Logger, schedule *shared.DeploymentSchedule) error {
+	if schedule == nil {
+		return nil
+	}
+
+	wait, err := schedule.WaitDuration(workflow.Now(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to evaluate deployment schedule: %w", err)
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	logger.Info("Outside deployment window, waiting for next window (or override-window signal)", "wait", wait)
+
+	overrideChannel := workflow.GetSignalChannel(ctx, "override-window")
+	timer := workflow.NewTimer(ctx, wait)
+	selector := workflow.NewSelector(ctx)
+
+	selector.AddFuture(timer, func(f workflow.Future) {
+		logger.Info("Deployment window reached")
+	})
+	selector.AddReceive(overrideChannel, func(c workflow.ReceiveChannel, more bool) {
+		var override shared.OverrideWindowSignal
+		c.Receive(ctx, &override)
+		logger.Info("Deployment window overridden", "approver", override.Approver, "reason", override.Reason)
+	})
+	selector.Select(ctx)
+
+	return nil
+}
+
+// waitForApprovalDecision waits on the "approval" signal channel, escalating to a
+// secondary approver after ApprovalChannelConfig.EscalationTimeout and
+// auto-rejecting if the escalation window also elapses with no decision. This
+// keeps a stalled human-approval channel from hanging the pipeline forever.
+func waitForApprovalDecision(ctx workflow.Context, logger log.Logger, fullImagePath string, config shared.ApprovalChannelConfig) (shared.ApprovalSignal, error) {
+	approvalChannel := workflow.GetSignalChannel(ctx, "approval")
+	var signal shared.ApprovalSignal
+
+	timeout := config.EscalationTimeout
+	if timeout <= 0 {
+		// No escalation configured: wait indefinitely, matching the original behavior.
+		approvalChannel.Receive(ctx, &signal)
+		return signal, nil
+	}
+
+	escalated := false
+	for attempt := 0; attempt < 2; attempt++ {
+		received := false
+		timer := workflow.NewTimer(ctx, timeout)
+		selector := workflow.NewSelector(ctx)
+		selector.AddFuture(timer, func(f workflow.Future) {})
+		selector.AddReceive(approvalChannel, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, &signal)
+			received = true
+		})
+		selector.Select(ctx)
+
+		if received {
+			return signal, nil
+		}
+
+		if escalated {
+			break
+		}
+
+		logger.Warn("No approval decision received, escalating to secondary approver", "timeout", timeout)
+		escalateReq := shared.EscalateApprovalRequest{
+			Environment:       "production",
+			ImageTag:          fullImagePath,
+			WorkflowID:        workflow.GetInfo(ctx).WorkflowExecution.ID,
+			SecondaryApprover: config.SecondaryApprover,
+		}
+		var escalateResp shared.EscalateApprovalResponse
+		if err := workflow.ExecuteActivity(ctx, "EscalateApproval", escalateReq).Get(ctx, &escalateResp); err != nil {
+			logger.Error("Escalation activity failed", "error", err)
+		}
+		escalated = true
+	}
+
+	logger.Warn("Approval timed out after escalation, auto-rejecting")
+	return shared.ApprovalSignal{
+		Approved: false,
+		Approver: "system",
+		Reason:   "auto-rejected: no approval decision after escalation timeout",
+	}, nil
+}